@@ -2,9 +2,13 @@ package cmd
 
 import (
 	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 
+	"github.com/hbelmiro/kgrep/internal/log"
 	"github.com/spf13/cobra"
 )
 
@@ -13,6 +17,7 @@ func resetFlags() {
 	resourcesNamespace = ""
 	resourcesPattern = ""
 	resourcesAllNamespaces = false
+	resourcesWhere = ""
 
 	podsNamespace = ""
 	podsPattern = ""
@@ -34,6 +39,33 @@ func resetFlags() {
 	logsResource = ""
 	logsPattern = ""
 	logsSortBy = ""
+	logsWhere = ""
+	logsFields = nil
+	logsLevels = nil
+	resourcesMatch = matchFlags{}
+	podsMatch = matchFlags{}
+	configmapsMatch = matchFlags{}
+	secretsMatch = matchFlags{}
+	serviceaccountsMatch = matchFlags{}
+	logsMatch = matchFlags{}
+	logsContext = contextFlags{}
+	logsConcurrency = defaultConcurrency
+	logsChunkSize = defaultChunkSize
+	logsMaxLineLength = log.DefaultMaxLineLength
+	logsWindow = windowFlags{tail: -1}
+	logsPrevious = false
+	logsIncludePrevious = false
+	logsContainers = nil
+	logsContainerTypes = nil
+	logsFollow = false
+	logsAllNamespaces = false
+	logsPodNameContains = ""
+	logsPods = podSelectorFlags{}
+	resourcesSearch = searchFlags{concurrency: defaultConcurrency, chunkSize: defaultChunkSize}
+	podsSearch = searchFlags{concurrency: defaultConcurrency, chunkSize: defaultChunkSize}
+	configmapsSearch = searchFlags{concurrency: defaultConcurrency, chunkSize: defaultChunkSize}
+	secretsSearch = searchFlags{concurrency: defaultConcurrency, chunkSize: defaultChunkSize}
+	serviceaccountsSearch = searchFlags{concurrency: defaultConcurrency, chunkSize: defaultChunkSize}
 }
 
 func executeCommand(root *cobra.Command, args ...string) (output string, err error) {
@@ -68,35 +100,35 @@ func TestResourcesCommand_MissingFlags(t *testing.T) {
 
 func TestPodsCommand_MissingFlags(t *testing.T) {
 	_, err := executeCommand(rootCmd, "pods")
-	if err == nil || err.Error() != "required flag(s) \"pattern\" not set" {
+	if err == nil || err.Error() != "at least one of the flags in the group [pattern regexp pattern-file] is required" {
 		t.Errorf("Expected error for missing required flags, got: %v", err)
 	}
 }
 
 func TestConfigMapsCommand_MissingFlags(t *testing.T) {
 	_, err := executeCommand(rootCmd, "configmaps")
-	if err == nil || err.Error() != "required flag(s) \"pattern\" not set" {
+	if err == nil || err.Error() != "at least one of the flags in the group [pattern regexp pattern-file] is required" {
 		t.Errorf("Expected error for missing required flags, got: %v", err)
 	}
 }
 
 func TestSecretsCommand_MissingFlags(t *testing.T) {
 	_, err := executeCommand(rootCmd, "secrets")
-	if err == nil || err.Error() != "required flag(s) \"pattern\" not set" {
+	if err == nil || err.Error() != "at least one of the flags in the group [pattern regexp pattern-file] is required" {
 		t.Errorf("Expected error for missing required flags, got: %v", err)
 	}
 }
 
 func TestServiceAccountsCommand_MissingFlags(t *testing.T) {
 	_, err := executeCommand(rootCmd, "serviceaccounts")
-	if err == nil || err.Error() != "required flag(s) \"pattern\" not set" {
+	if err == nil || err.Error() != "at least one of the flags in the group [pattern regexp pattern-file] is required" {
 		t.Errorf("Expected error for missing required flags, got: %v", err)
 	}
 }
 
 func TestLogsCommand_MissingFlags(t *testing.T) {
 	_, err := executeCommand(rootCmd, "logs")
-	if err == nil || err.Error() != "required flag(s) \"pattern\" not set" {
+	if err == nil || err.Error() != "at least one of the flags in the group [pattern regexp pattern-file where level] is required" {
 		t.Errorf("Expected error for missing required flags, got: %v", err)
 	}
 }
@@ -386,3 +418,383 @@ func TestAllNamespacesFlagAccepted_Pods(t *testing.T) {
 		t.Logf("Expected error for kubeconfig/connectivity issues: %v", err)
 	}
 }
+
+func TestMatchFlagsValidation(t *testing.T) {
+	testCases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "regexp and fixed strings",
+			args:     []string{"configmaps", "--pattern", "test", "-E", "-F"},
+			expected: "--extended-regexp and --fixed-strings cannot be used together",
+		},
+		{
+			name:     "invalid query",
+			args:     []string{"secrets", "--pattern", "(postgres OR mysql", "--query"},
+			expected: "missing ')'",
+		},
+		{
+			name:     "invalid scope",
+			args:     []string{"pods", "-e", "test", "--scope", "document"},
+			expected: "invalid scope \"document\"",
+		},
+		{
+			name:     "keys only and values only",
+			args:     []string{"configmaps", "-p", "test", "--keys-only", "--values-only"},
+			expected: "--keys-only and --values-only cannot be used together",
+		},
+		{
+			name:     "invalid field selector",
+			args:     []string{"pods", "-p", "test", "--field", "spec.containers[x]"},
+			expected: "invalid field selector",
+		},
+		{
+			name:     "invalid label selector",
+			args:     []string{"configmaps", "-p", "test", "-l", "app in (checkout"},
+			expected: "invalid label selector",
+		},
+		{
+			name:     "invalid field selector",
+			args:     []string{"secrets", "-p", "test", "--field-selector", "metadata.name"},
+			expected: "invalid field selector",
+		},
+		{
+			name:     "invalid annotation",
+			args:     []string{"serviceaccounts", "-p", "test", "--annotation", "=team-a"},
+			expected: "invalid annotation requirement",
+		},
+		{
+			name:     "invalid name pattern",
+			args:     []string{"configmaps", "web-[a", "-p", "test"},
+			expected: "invalid name pattern",
+		},
+		{
+			name:     "invalid resource concurrency",
+			args:     []string{"pods", "-p", "test", "--concurrency", "0"},
+			expected: "invalid concurrency 0: must be at least 1",
+		},
+		{
+			name:     "invalid logs concurrency",
+			args:     []string{"logs", "-p", "test", "--concurrency", "-2"},
+			expected: "invalid concurrency -2: must be at least 1",
+		},
+		{
+			name:     "invalid resource chunk size",
+			args:     []string{"configmaps", "-p", "test", "--chunk-size", "-1"},
+			expected: "invalid chunk size -1: cannot be negative",
+		},
+		{
+			name:     "invalid logs chunk size",
+			args:     []string{"logs", "-p", "test", "--chunk-size", "-5"},
+			expected: "invalid chunk size -5: cannot be negative",
+		},
+		{
+			name:     "invalid max line length",
+			args:     []string{"logs", "-p", "test", "--max-line-length", "0"},
+			expected: "invalid max line length 0: must be at least 1",
+		},
+		{
+			name:     "invalid since time",
+			args:     []string{"logs", "-p", "test", "--since-time", "yesterday"},
+			expected: `invalid --since-time "yesterday"`,
+		},
+		{
+			name:     "invalid until",
+			args:     []string{"logs", "-p", "test", "--until", "14:10"},
+			expected: `invalid --until "14:10"`,
+		},
+		{
+			name:     "until before since time",
+			args:     []string{"logs", "-p", "test", "--since-time", "2025-03-01T14:10:00Z", "--until", "2025-03-01T14:02:00Z"},
+			expected: "--until cannot be before --since-time",
+		},
+		{
+			name:     "since and since time",
+			args:     []string{"logs", "-p", "test", "--since", "15m", "--since-time", "2025-03-01T14:10:00Z"},
+			expected: "--since and --since-time cannot be used together",
+		},
+		{
+			name:     "negative since",
+			args:     []string{"logs", "-p", "test", "--since", "-5m"},
+			expected: "invalid --since -5m0s: cannot be negative",
+		},
+		{
+			name:     "zero tail",
+			args:     []string{"logs", "-p", "test", "--tail", "0"},
+			expected: "invalid --tail 0: must be positive, or -1 to search whole logs",
+		},
+		{
+			name:     "previous and include previous",
+			args:     []string{"logs", "-p", "test", "--previous", "--include-previous"},
+			expected: "--previous and --include-previous cannot be used together",
+		},
+		{
+			name:     "logs all namespaces and namespace",
+			args:     []string{"logs", "-p", "test", "-A", "-n", "default"},
+			expected: "--all-namespaces and --namespace cannot be used together",
+		},
+		{
+			name:     "resource and pod name substring",
+			args:     []string{"logs", "-p", "test", "-r", "deployment/api", "--pod-name-contains", "api"},
+			expected: "--resource and --pod-name-contains cannot be used together",
+		},
+		{
+			name:     "invalid logs label selector",
+			args:     []string{"logs", "-p", "test", "-l", "app in (checkout"},
+			expected: "invalid label selector",
+		},
+		{
+			name:     "invalid phase",
+			args:     []string{"logs", "-p", "test", "--phase", "Crashing"},
+			expected: `invalid phase "Crashing": must be one of: Pending, Running, Succeeded, Failed, Unknown`,
+		},
+		{
+			name:     "follow previous instances",
+			args:     []string{"logs", "-p", "test", "--follow", "--previous"},
+			expected: "--follow cannot be used with --previous or --include-previous",
+		},
+		{
+			name:     "follow until",
+			args:     []string{"logs", "-p", "test", "--follow", "--until", "2024-05-01T10:00:00Z"},
+			expected: "--follow cannot be used with --until",
+		},
+		{
+			name:     "follow sorted",
+			args:     []string{"logs", "-p", "test", "--follow", "--sort-by", "message"},
+			expected: "--follow cannot be used with --sort-by message",
+		},
+		{
+			name:     "invalid container pattern",
+			args:     []string{"logs", "-p", "test", "-c", "istio-[a"},
+			expected: `invalid container pattern "istio-[a"`,
+		},
+		{
+			name:     "invalid container type",
+			args:     []string{"logs", "-p", "test", "--container-type", "init,daemon"},
+			expected: `invalid container type "daemon"`,
+		},
+		{
+			name:     "missing pattern file",
+			args:     []string{"serviceaccounts", "--pattern-file", "/non/existent/patterns.txt"},
+			expected: "failed to read pattern file",
+		},
+		{
+			name:     "negative context",
+			args:     []string{"logs", "--pattern", "test", "-C", "-1"},
+			expected: "context line counts cannot be negative",
+		},
+		{
+			name:     "invalid where expression",
+			args:     []string{"resources", "--kind", "Deployment", "--where", "object.spec.replicas >"},
+			expected: "invalid expression",
+		},
+		{
+			name:     "where expression not evaluating to a bool",
+			args:     []string{"resources", "--kind", "Deployment", "--where", "object.spec.replicas + 1"},
+			expected: "must evaluate to a bool",
+		},
+		{
+			name:     "invalid logs where expression",
+			args:     []string{"logs", "--where", "level =="},
+			expected: "invalid expression",
+		},
+		{
+			name:     "logs where expression not evaluating to a bool",
+			args:     []string{"logs", "--where", "latency_ms + 1"},
+			expected: "must evaluate to a bool",
+		},
+		{
+			name:     "invalid log level",
+			args:     []string{"logs", "--level", "verbose+"},
+			expected: `invalid level "verbose+"`,
+		},
+		{
+			name:     "invalid logs field",
+			args:     []string{"logs", "--pattern", "test", "--fields", "msg,.trace_id"},
+			expected: `invalid field ".trace_id"`,
+		},
+		{
+			name:     "invalid regular expression",
+			args:     []string{"resources", "--kind", "Pod", "--pattern", "(unclosed", "-E"},
+			expected: "invalid pattern",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			output, err := executeCommand(rootCmd, tc.args...)
+			if err == nil {
+				t.Fatalf("Expected error, got output: %s", output)
+			}
+
+			if !strings.Contains(err.Error(), tc.expected) {
+				t.Errorf("Expected error containing %q, got: %v", tc.expected, err)
+			}
+		})
+	}
+}
+
+func TestLogsCommand_FollowShorthand(t *testing.T) {
+	// -f is --follow on logs, so the follow restrictions apply.
+	_, err := executeCommand(rootCmd, "logs", "-f", "-p", "error", "--until", "2024-05-01T10:00:00Z")
+	if err == nil || err.Error() != "--follow cannot be used with --until" {
+		t.Errorf("Expected -f to enable --follow, got: %v", err)
+	}
+
+	if flag := logsCmd.Flags().Lookup("pattern-file"); flag.Shorthand != "" {
+		t.Errorf("Expected --pattern-file to have no shorthand on logs, got -%s", flag.Shorthand)
+	}
+	if flag := podsCmd.Flags().ShorthandLookup("f"); flag == nil || flag.Name != "pattern-file" {
+		t.Errorf("Expected -f to be --pattern-file on pods, got: %v", flag)
+	}
+}
+
+func TestMatchFlags_CaseLastFlagWins(t *testing.T) {
+	testCases := []struct {
+		args          []string
+		caseSensitive bool
+	}{
+		{args: nil, caseSensitive: false},
+		{args: []string{"-i"}, caseSensitive: false},
+		{args: []string{"--case-sensitive"}, caseSensitive: true},
+		{args: []string{"-i", "--case-sensitive"}, caseSensitive: true},
+		{args: []string{"--case-sensitive", "-i"}, caseSensitive: false},
+		{args: []string{"--ignore-case=false"}, caseSensitive: true},
+	}
+
+	for _, tc := range testCases {
+		var flags matchFlags
+		cmd := &cobra.Command{}
+		addMatchFlags(cmd, &flags)
+		if err := cmd.ParseFlags(tc.args); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if flags.caseSensitive != tc.caseSensitive {
+			t.Errorf("Expected case sensitive %v for %v, got %v", tc.caseSensitive, tc.args, flags.caseSensitive)
+		}
+	}
+}
+
+func TestMatchFlags_PatternFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "patterns.txt")
+	if err := os.WriteFile(path, []byte("ghp_leaked\r\n\nold.example.com\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write pattern file: %v", err)
+	}
+
+	flags := &matchFlags{patternFiles: []string{path}}
+	matcher, err := flags.newMatcher("")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	spans, ok := matcher.Match("url: https://old.example.com")
+	if !ok || len(spans) != 1 || spans[0].Pattern != "old.example.com" {
+		t.Errorf("Expected a match attributed to old.example.com, got: %v", spans)
+	}
+
+	if _, ok := matcher.Match("nothing here"); ok {
+		t.Errorf("Expected no match")
+	}
+
+	if !flags.multiplePatterns("") {
+		t.Errorf("Expected pattern files to report multiple patterns")
+	}
+
+	emptyPath := filepath.Join(dir, "empty.txt")
+	if err := os.WriteFile(emptyPath, []byte("\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write pattern file: %v", err)
+	}
+
+	_, err = (&matchFlags{patternFiles: []string{emptyPath}}).newMatcher("")
+	if err == nil || !strings.Contains(err.Error(), "no patterns found") {
+		t.Errorf("Expected error for empty pattern file, got: %v", err)
+	}
+}
+
+func TestContextFlags_Lines(t *testing.T) {
+	testCases := []struct {
+		args   []string
+		before int
+		after  int
+	}{
+		{args: nil, before: 0, after: 0},
+		{args: []string{"-C", "3"}, before: 3, after: 3},
+		{args: []string{"-C", "3", "-B", "1"}, before: 1, after: 3},
+		{args: []string{"-C", "3", "-B", "0"}, before: 0, after: 3},
+		{args: []string{"-C", "3", "--after-context", "0"}, before: 3, after: 0},
+		{args: []string{"--after-context", "2"}, before: 0, after: 2},
+	}
+
+	for _, tc := range testCases {
+		var flags contextFlags
+		cmd := &cobra.Command{}
+		addContextFlags(cmd, &flags)
+		if err := cmd.ParseFlags(tc.args); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		before, after, err := flags.lines(cmd)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if before != tc.before || after != tc.after {
+			t.Errorf("Expected %d lines before and %d after for %v, got %d and %d", tc.before, tc.after, tc.args, before, after)
+		}
+	}
+}
+
+func TestProjectFields(t *testing.T) {
+	values := map[string]interface{}{
+		"msg":         "request failed",
+		"trace_id":    "4bf92f35",
+		"latency_ms":  int64(730),
+		"http":        map[string]interface{}{"status": int64(503), "path": "/api"},
+		"http.method": "GET",
+		"tags":        []interface{}{"a", "b"},
+		"empty":       "",
+		"error":       nil,
+	}
+
+	testCases := []struct {
+		fields   []string
+		expected string
+	}{
+		{fields: []string{"msg", "trace_id"}, expected: `msg="request failed" trace_id=4bf92f35`},
+		{fields: []string{"latency_ms", "http.status", "http.method"}, expected: "latency_ms=730 http.status=503 http.method=GET"},
+		{fields: []string{"http", "tags"}, expected: `http={"path":"/api","status":503} tags=["a","b"]`},
+		{fields: []string{"empty", "error"}, expected: `empty="" error=null`},
+		{fields: []string{"missing", "msg.length", "msg"}, expected: `msg="request failed"`},
+	}
+
+	for _, tc := range testCases {
+		if actual := projectFields(values, tc.fields); actual != tc.expected {
+			t.Errorf("Expected %s for fields %v, got %s", tc.expected, tc.fields, actual)
+		}
+	}
+}
+
+func TestParseLevels(t *testing.T) {
+	testCases := []struct {
+		names    []string
+		expected []log.Level
+	}{
+		{names: []string{"warn+"}, expected: []log.Level{log.LevelWarn, log.LevelError, log.LevelFatal}},
+		{names: []string{"ERROR"}, expected: []log.Level{log.LevelError}},
+		{names: []string{"debug", "warning+"}, expected: []log.Level{log.LevelDebug, log.LevelWarn, log.LevelError, log.LevelFatal}},
+		{names: []string{"error+", "fatal"}, expected: []log.Level{log.LevelError, log.LevelFatal}},
+		{names: nil, expected: nil},
+	}
+
+	for _, tc := range testCases {
+		levels, err := parseLevels(tc.names)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !slices.Equal(levels, tc.expected) {
+			t.Errorf("Expected levels %v for %v, got %v", tc.expected, tc.names, levels)
+		}
+	}
+}
@@ -11,16 +11,19 @@ var (
 	configmapsNamespace     string
 	configmapsPattern       string
 	configmapsAllNamespaces bool
+	configmapsMatch         matchFlags
+	configmapsSearch        searchFlags
 )
 
 var configmapsCmd = &cobra.Command{
-	Use:   "configmaps",
+	Use:   "configmaps [flags] [NAME...]",
 	Short: "Search ConfigMaps in Kubernetes",
-	Long:  `Search the content of ConfigMaps for specific patterns within designated namespaces.`,
+	Long: `Search the content of ConfigMaps for specific patterns within designated namespaces.
+Optionally restrict the search to the named objects; names may be glob patterns like web-*.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// For runtime errors, we don't want to show usage
 		cmd.SilenceUsage = true
-		if configmapsPattern == "" {
+		if !configmapsMatch.hasPattern(configmapsPattern) {
 			return fmt.Errorf("pattern is required")
 		}
 
@@ -28,30 +31,41 @@ var configmapsCmd = &cobra.Command{
 			return fmt.Errorf("--all-namespaces and --namespace cannot be used together")
 		}
 
+		matcher, err := configmapsMatch.newMatcher(configmapsPattern)
+		if err != nil {
+			return err
+		}
+
+		options, err := configmapsSearch.options(cmd, args)
+		if err != nil {
+			return err
+		}
+
 		resourceSearcher, err := resource.NewResourceSearcher("configmaps")
 		if err != nil {
 			return fmt.Errorf("failed to create resource searcher: %v", err)
 		}
+		resourceSearcher.SetOptions(options)
 
-		var occurrences []resource.Occurrence
+		printer := newOccurrencePrinter(configmapsMatch.description(configmapsPattern), configmapsMatch.multiplePatterns(configmapsPattern), configmapsSearch.enabled())
 		if configmapsAllNamespaces {
-			occurrences, err = resourceSearcher.SearchAllNamespaces(configmapsPattern)
+			err = resourceSearcher.SearchAllNamespacesStream(matcher, printer.print)
 			if err != nil {
 				return fmt.Errorf("failed to search configmaps: %v", err)
 			}
 		} else if configmapsNamespace != "" {
-			occurrences, err = resourceSearcher.Search(configmapsNamespace, configmapsPattern)
+			err = resourceSearcher.SearchStream(configmapsNamespace, matcher, printer.print)
 			if err != nil {
 				return fmt.Errorf("failed to search configmaps: %v", err)
 			}
 		} else {
-			occurrences, err = resourceSearcher.SearchWithoutNamespace(configmapsPattern)
+			err = resourceSearcher.SearchWithoutNamespaceStream(matcher, printer.print)
 			if err != nil {
 				return fmt.Errorf("failed to search configmaps: %v", err)
 			}
 		}
 
-		printResourceOccurrences(occurrences, configmapsPattern)
+		printer.finish()
 
 		return nil
 	},
@@ -63,8 +77,8 @@ func init() {
 	configmapsCmd.Flags().StringVarP(&configmapsNamespace, "namespace", "n", "", "The Kubernetes namespace")
 	configmapsCmd.Flags().StringVarP(&configmapsPattern, "pattern", "p", "", "grep search pattern")
 	configmapsCmd.Flags().BoolVarP(&configmapsAllNamespaces, "all-namespaces", "A", false, "If present, list the requested object(s) across all namespaces")
+	addMatchFlags(configmapsCmd, &configmapsMatch)
+	addSearchFlags(configmapsCmd, &configmapsSearch)
 
-	if err := configmapsCmd.MarkFlagRequired("pattern"); err != nil {
-		panic(fmt.Sprintf("failed to mark pattern flag as required: %v", err))
-	}
+	configmapsCmd.MarkFlagsOneRequired("pattern", "regexp", "pattern-file")
 }
@@ -0,0 +1,472 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hbelmiro/kgrep/internal/log"
+	"github.com/hbelmiro/kgrep/internal/match"
+	"github.com/hbelmiro/kgrep/internal/resource"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// matchFlags holds the pattern matching flags shared by all search commands.
+type matchFlags struct {
+	patterns       []string
+	patternFiles   []string
+	query          bool
+	invertMatch    bool
+	extendedRegexp bool
+	fixedStrings   bool
+	caseSensitive  bool
+	wordRegexp     bool
+}
+
+func addMatchFlags(cmd *cobra.Command, flags *matchFlags) {
+	cmd.Flags().StringArrayVarP(&flags.patterns, "regexp", "e", nil, "Additional search pattern; may be repeated, lines matching any pattern are reported")
+	// logs takes -f for --follow, as kubectl logs does, so --pattern-file has no shorthand there.
+	patternFileShorthand := "f"
+	if cmd.Flags().ShorthandLookup(patternFileShorthand) != nil {
+		patternFileShorthand = ""
+	}
+	cmd.Flags().StringArrayVarP(&flags.patternFiles, "pattern-file", patternFileShorthand, nil, "Read patterns from a file, one per line; may be repeated")
+	cmd.Flags().BoolVarP(&flags.query, "query", "q", false, "Interpret patterns as boolean queries using AND, OR, NOT, parentheses and quoted phrases")
+	cmd.Flags().BoolVarP(&flags.invertMatch, "invert-match", "v", false, "Select non-matching lines")
+	cmd.Flags().BoolVarP(&flags.extendedRegexp, "extended-regexp", "E", false, "Interpret the pattern as an RE2 regular expression")
+	cmd.Flags().BoolVarP(&flags.fixedStrings, "fixed-strings", "F", false, "Interpret the pattern as a fixed string (default)")
+	cmd.Flags().VarPF(caseFlag{caseSensitive: &flags.caseSensitive}, "ignore-case", "i", "Ignore case distinctions; overrides an earlier --case-sensitive").NoOptDefVal = "true"
+	cmd.Flags().VarPF(caseFlag{caseSensitive: &flags.caseSensitive, sensitive: true}, "case-sensitive", "", "Match case exactly; overrides an earlier --ignore-case").NoOptDefVal = "true"
+	cmd.Flags().BoolVarP(&flags.wordRegexp, "word-regexp", "w", false, "Only match whole words")
+}
+
+// caseFlag is a boolean flag setting whether case is respected. --ignore-case
+// and --case-sensitive set the same value, so the last one given wins, as with
+// grep's -i and --no-ignore-case.
+type caseFlag struct {
+	caseSensitive *bool
+	// sensitive is whether case is respected when the flag is true.
+	sensitive bool
+}
+
+func (f caseFlag) Set(value string) error {
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		return err
+	}
+	*f.caseSensitive = enabled == f.sensitive
+	return nil
+}
+
+func (f caseFlag) String() string {
+	if f.caseSensitive == nil {
+		return "false"
+	}
+	return strconv.FormatBool(*f.caseSensitive == f.sensitive)
+}
+
+func (f caseFlag) Type() string {
+	return "bool"
+}
+
+// hasPattern reports whether a pattern was given as the pattern argument, with --regexp or with --pattern-file.
+func (f *matchFlags) hasPattern(pattern string) bool {
+	return pattern != "" || len(f.patterns) > 0 || len(f.patternFiles) > 0
+}
+
+// multiplePatterns reports whether more than one pattern may be searched for,
+// in which case it's worth showing which pattern matched.
+func (f *matchFlags) multiplePatterns(pattern string) bool {
+	count := len(f.patterns)
+	if pattern != "" {
+		count++
+	}
+	return count > 1 || len(f.patternFiles) > 0
+}
+
+// description describes the searched patterns for messages shown to the user.
+func (f *matchFlags) description(pattern string) string {
+	patterns := f.patterns
+	if pattern != "" {
+		patterns = append([]string{pattern}, patterns...)
+	}
+	for _, file := range f.patternFiles {
+		patterns = append(patterns, "patterns from "+file)
+	}
+	return strings.Join(patterns, "', '")
+}
+
+// newMatcher compiles the pattern, the --regexp patterns and the patterns read
+// from --pattern-file according to the matching flags.
+func (f *matchFlags) newMatcher(pattern string) (match.Matcher, error) {
+	if f.extendedRegexp && f.fixedStrings {
+		return nil, fmt.Errorf("--extended-regexp and --fixed-strings cannot be used together")
+	}
+
+	options := match.Options{
+		Regexp:        f.extendedRegexp,
+		CaseSensitive: f.caseSensitive,
+		WholeWord:     f.wordRegexp,
+	}
+
+	patterns := f.patterns
+	if pattern != "" || (len(patterns) == 0 && len(f.patternFiles) == 0) {
+		patterns = append([]string{pattern}, patterns...)
+	}
+
+	var matchers []match.Matcher
+	var plainPatterns []string
+	if f.query {
+		for _, p := range patterns {
+			query, err := match.ParseQuery(p, options)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern: %v", err)
+			}
+			matchers = append(matchers, query)
+		}
+	} else {
+		plainPatterns = append(plainPatterns, patterns...)
+	}
+
+	for _, file := range f.patternFiles {
+		filePatterns, err := readPatternFile(file)
+		if err != nil {
+			return nil, err
+		}
+		plainPatterns = append(plainPatterns, filePatterns...)
+	}
+
+	if len(plainPatterns) > 0 {
+		// Plain patterns are matched together so each line is scanned only once.
+		set, err := match.NewSet(plainPatterns, options)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern: %v", err)
+		}
+		matchers = append(matchers, set)
+	}
+
+	if len(matchers) == 0 {
+		return nil, fmt.Errorf("no patterns found in %s", strings.Join(f.patternFiles, ", "))
+	}
+
+	matcher := match.Any(matchers...)
+	if f.invertMatch {
+		matcher = match.Not(matcher)
+	}
+
+	return matcher, nil
+}
+
+// readPatternFile reads the non-empty lines of a file as patterns.
+func readPatternFile(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pattern file: %v", err)
+	}
+
+	var patterns []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if line != "" {
+			patterns = append(patterns, line)
+		}
+	}
+	return patterns, nil
+}
+
+// contextFlags holds the flags controlling the lines printed around matches.
+type contextFlags struct {
+	before  int
+	after   int
+	context int
+}
+
+func addContextFlags(cmd *cobra.Command, flags *contextFlags) {
+	// -A is taken by --all-namespaces, so --after-context has no shorthand.
+	cmd.Flags().IntVar(&flags.after, "after-context", 0, "Print NUM lines of trailing context after matching lines")
+	cmd.Flags().IntVarP(&flags.before, "before-context", "B", 0, "Print NUM lines of leading context before matching lines")
+	cmd.Flags().IntVarP(&flags.context, "context", "C", 0, "Print NUM lines of leading and trailing context; --after-context and --before-context take precedence")
+}
+
+// lines returns the number of lines of leading and trailing context. Counts
+// given with --before-context or --after-context, even 0, override --context.
+func (f *contextFlags) lines(cmd *cobra.Command) (before, after int, err error) {
+	if f.before < 0 || f.after < 0 || f.context < 0 {
+		return 0, 0, fmt.Errorf("context line counts cannot be negative")
+	}
+
+	before, after = f.before, f.after
+	if !cmd.Flags().Changed("before-context") {
+		before = f.context
+	}
+	if !cmd.Flags().Changed("after-context") {
+		after = f.context
+	}
+	return before, after, nil
+}
+
+// enabled reports whether context lines were requested.
+func (f *contextFlags) enabled() bool {
+	return f.before > 0 || f.after > 0 || f.context > 0
+}
+
+// defaultConcurrency is the default number of API requests made at once.
+const defaultConcurrency = 8
+
+func addConcurrencyFlag(cmd *cobra.Command, concurrency *int) {
+	cmd.Flags().IntVar(concurrency, "concurrency", defaultConcurrency, "Maximum number of namespaces, resources or containers searched at once")
+}
+
+func validateConcurrency(concurrency int) error {
+	if concurrency < 1 {
+		return fmt.Errorf("invalid concurrency %d: must be at least 1", concurrency)
+	}
+	return nil
+}
+
+// defaultChunkSize is the default number of objects listed per request, as in kubectl.
+const defaultChunkSize = 500
+
+func addChunkSizeFlag(cmd *cobra.Command, chunkSize *int64) {
+	cmd.Flags().Int64Var(chunkSize, "chunk-size", defaultChunkSize, "Return large lists in chunks rather than all at once; pass 0 to disable")
+}
+
+func validateChunkSize(chunkSize int64) error {
+	if chunkSize < 0 {
+		return fmt.Errorf("invalid chunk size %d: cannot be negative", chunkSize)
+	}
+	return nil
+}
+
+// windowFlags holds the flags restricting log searches to a time window.
+type windowFlags struct {
+	since     time.Duration
+	sinceTime string
+	until     string
+	tail      int64
+}
+
+func addWindowFlags(cmd *cobra.Command, flags *windowFlags) {
+	cmd.Flags().DurationVar(&flags.since, "since", 0, "Only search lines newer than a relative duration like 15m or 2h")
+	cmd.Flags().StringVar(&flags.sinceTime, "since-time", "", "Only search lines logged after a date (RFC3339), e.g. 2025-03-01T14:02:00Z")
+	cmd.Flags().StringVar(&flags.until, "until", "", "Only search lines logged up to a date (RFC3339), e.g. 2025-03-01T14:10:00Z")
+	cmd.Flags().Int64Var(&flags.tail, "tail", -1, "Only search the last NUM lines of each log; -1 searches whole logs")
+}
+
+// apply sets the time window of log search options.
+func (f *windowFlags) apply(options *log.Options) error {
+	if f.since != 0 && f.sinceTime != "" {
+		return fmt.Errorf("--since and --since-time cannot be used together")
+	}
+	if f.since < 0 {
+		return fmt.Errorf("invalid --since %v: cannot be negative", f.since)
+	}
+	options.Since = f.since
+
+	var err error
+	if f.sinceTime != "" {
+		options.SinceTime, err = time.Parse(time.RFC3339, f.sinceTime)
+		if err != nil {
+			return fmt.Errorf("invalid --since-time %q: must be an RFC3339 date like 2025-03-01T14:02:00Z", f.sinceTime)
+		}
+	}
+
+	if f.until != "" {
+		options.Until, err = time.Parse(time.RFC3339, f.until)
+		if err != nil {
+			return fmt.Errorf("invalid --until %q: must be an RFC3339 date like 2025-03-01T14:10:00Z", f.until)
+		}
+		if !options.SinceTime.IsZero() && options.Until.Before(options.SinceTime) {
+			return fmt.Errorf("--until cannot be before --since-time")
+		}
+	}
+
+	switch {
+	case f.tail == 0 || f.tail < -1:
+		return fmt.Errorf("invalid --tail %d: must be positive, or -1 to search whole logs", f.tail)
+	case f.tail > 0:
+		options.TailLines = f.tail
+	}
+
+	return nil
+}
+
+// podSelectorFlags holds the flags selecting the pods whose logs are searched.
+type podSelectorFlags struct {
+	labelSelector string
+	fieldSelector string
+	phase         string
+	node          string
+}
+
+func addPodSelectorFlags(cmd *cobra.Command, flags *podSelectorFlags) {
+	cmd.Flags().StringVarP(&flags.labelSelector, "selector", "l", "", "Only search pods matching a label selector, e.g. app=checkout,tier=backend")
+	cmd.Flags().StringVar(&flags.fieldSelector, "field-selector", "", "Only search pods matching a field selector, e.g. spec.serviceAccountName=checkout")
+	cmd.Flags().StringVar(&flags.phase, "phase", "", "Only search pods in a phase: Pending, Running, Succeeded, Failed, Unknown")
+	cmd.Flags().StringVar(&flags.node, "node", "", "Only search pods scheduled on a node")
+}
+
+// podPhases lists the valid pod phases.
+var podPhases = []corev1.PodPhase{corev1.PodPending, corev1.PodRunning, corev1.PodSucceeded, corev1.PodFailed, corev1.PodUnknown}
+
+// apply sets the pod selectors of log search options.
+func (f *podSelectorFlags) apply(options *log.Options) error {
+	if _, err := labels.Parse(f.labelSelector); err != nil {
+		return fmt.Errorf("invalid label selector: %v", err)
+	}
+	options.LabelSelector = f.labelSelector
+
+	if _, err := fields.ParseSelector(f.fieldSelector); err != nil {
+		return fmt.Errorf("invalid field selector: %v", err)
+	}
+	options.FieldSelector = f.fieldSelector
+
+	if f.phase != "" {
+		i := slices.IndexFunc(podPhases, func(phase corev1.PodPhase) bool {
+			return strings.EqualFold(string(phase), f.phase)
+		})
+		if i < 0 {
+			return fmt.Errorf("invalid phase %q: must be one of: Pending, Running, Succeeded, Failed, Unknown", f.phase)
+		}
+		options.Phase = podPhases[i]
+	}
+
+	options.NodeName = f.node
+	return nil
+}
+
+// searchFlags holds the flags shared by the resource search commands.
+type searchFlags struct {
+	scope         string
+	fields        []string
+	excludeFields []string
+	keysOnly      bool
+	valuesOnly    bool
+	noise         resource.NoiseFilter
+	labelSelector string
+	fieldSelector string
+	annotations   []string
+	concurrency   int
+	chunkSize     int64
+	contextFlags
+}
+
+func addSearchFlags(cmd *cobra.Command, flags *searchFlags) {
+	cmd.Flags().StringVar(&flags.scope, "scope", string(resource.ScopeLine), "Evaluate patterns per line or per object: line, object")
+	cmd.Flags().StringArrayVar(&flags.fields, "field", nil, "Only search within the selected fields, e.g. spec.template.spec.containers[*].image; may be repeated")
+	cmd.Flags().StringArrayVar(&flags.excludeFields, "exclude-field", nil, "Skip the selected fields, e.g. metadata.annotations; may be repeated")
+	cmd.Flags().BoolVar(&flags.keysOnly, "keys-only", false, "Only match field keys")
+	cmd.Flags().BoolVar(&flags.valuesOnly, "values-only", false, "Only match field values")
+	cmd.Flags().BoolVar(&flags.noise.IncludeManagedFields, "include-managed-fields", false, "Search metadata.managedFields, which is skipped by default")
+	cmd.Flags().BoolVar(&flags.noise.IncludeStatus, "include-status", false, "Search the status of resources, which is skipped by default")
+	cmd.Flags().BoolVar(&flags.noise.Raw, "raw", false, "Search resources as returned by the API server, including managed fields, status, resourceVersion, uid and the last applied configuration")
+	cmd.Flags().StringVarP(&flags.labelSelector, "selector", "l", "", "Label selector to filter on, e.g. app=checkout,tier!=frontend")
+	cmd.Flags().StringVar(&flags.fieldSelector, "field-selector", "", "Field selector to filter on, e.g. metadata.name=my-config")
+	cmd.Flags().StringArrayVar(&flags.annotations, "annotation", nil, "Annotation to filter on: key, !key, key=value or key!=value; may be repeated")
+	addConcurrencyFlag(cmd, &flags.concurrency)
+	addChunkSizeFlag(cmd, &flags.chunkSize)
+	addContextFlags(cmd, &flags.contextFlags)
+}
+
+// options converts the flags of cmd and the object names given as arguments into resource search options.
+func (f *searchFlags) options(cmd *cobra.Command, names []string) (resource.Options, error) {
+	for _, name := range names {
+		if err := resource.ValidateName(name); err != nil {
+			return resource.Options{}, err
+		}
+	}
+
+	scope := resource.Scope(f.scope)
+	switch scope {
+	case "":
+		scope = resource.ScopeLine
+	case resource.ScopeLine, resource.ScopeObject:
+	default:
+		return resource.Options{}, fmt.Errorf("invalid scope %q: must be one of: line, object", f.scope)
+	}
+
+	before, after, err := f.lines(cmd)
+	if err != nil {
+		return resource.Options{}, err
+	}
+
+	includedFields, err := parseFieldSelectors(f.fields)
+	if err != nil {
+		return resource.Options{}, err
+	}
+
+	excludeFields, err := parseFieldSelectors(f.excludeFields)
+	if err != nil {
+		return resource.Options{}, err
+	}
+
+	if _, err := labels.Parse(f.labelSelector); err != nil {
+		return resource.Options{}, fmt.Errorf("invalid label selector: %v", err)
+	}
+
+	if _, err := fields.ParseSelector(f.fieldSelector); err != nil {
+		return resource.Options{}, fmt.Errorf("invalid field selector: %v", err)
+	}
+
+	var annotations []resource.AnnotationRequirement
+	for _, annotation := range f.annotations {
+		requirement, err := resource.ParseAnnotationRequirement(annotation)
+		if err != nil {
+			return resource.Options{}, err
+		}
+		annotations = append(annotations, requirement)
+	}
+
+	if err := validateConcurrency(f.concurrency); err != nil {
+		return resource.Options{}, err
+	}
+
+	if err := validateChunkSize(f.chunkSize); err != nil {
+		return resource.Options{}, err
+	}
+
+	target := resource.TargetAll
+	switch {
+	case f.keysOnly && f.valuesOnly:
+		return resource.Options{}, fmt.Errorf("--keys-only and --values-only cannot be used together")
+	case f.keysOnly:
+		target = resource.TargetKeys
+	case f.valuesOnly:
+		target = resource.TargetValues
+	}
+
+	return resource.Options{
+		Scope:         scope,
+		BeforeContext: before,
+		AfterContext:  after,
+		Fields:        includedFields,
+		ExcludeFields: excludeFields,
+		Target:        target,
+		Noise:         f.noise,
+		LabelSelector: f.labelSelector,
+		FieldSelector: f.fieldSelector,
+		Annotations:   annotations,
+		Names:         names,
+		Concurrency:   f.concurrency,
+		ChunkSize:     f.chunkSize,
+		OnError:       printWarning,
+	}, nil
+}
+
+func parseFieldSelectors(expressions []string) ([]resource.FieldSelector, error) {
+	var selectors []resource.FieldSelector
+	for _, expression := range expressions {
+		selector, err := resource.ParseFieldSelector(expression)
+		if err != nil {
+			return nil, err
+		}
+		selectors = append(selectors, selector)
+	}
+	return selectors, nil
+}
@@ -1,19 +1,46 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
+	"os/signal"
+	"path"
+	"slices"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/hbelmiro/kgrep/internal/log"
+	"github.com/hbelmiro/kgrep/internal/match"
+	"github.com/hbelmiro/kgrep/internal/predicate"
 	"github.com/spf13/cobra"
 )
 
 var (
-	logsNamespace string
-	logsResource  string
-	logsPattern   string
-	logsSortBy    string
+	logsNamespace       string
+	logsResource        string
+	logsPattern         string
+	logsSortBy          string
+	logsMatch           matchFlags
+	logsContext         contextFlags
+	logsConcurrency     int
+	logsChunkSize       int64
+	logsMaxLineLength   int
+	logsTimestamps      bool
+	logsWindow          windowFlags
+	logsPrevious        bool
+	logsIncludePrevious bool
+	logsContainers      []string
+	logsContainerTypes  []string
+	logsFollow          bool
+	logsAllNamespaces   bool
+	logsPodNameContains string
+	logsPods            podSelectorFlags
+	logsWhere           string
+	logsFields          []string
+	logsLevels          []string
 )
 
 var logsCmd = &cobra.Command{
@@ -25,44 +52,191 @@ var logsCmd = &cobra.Command{
 		cmd.SilenceUsage = true
 		color.NoColor = false // Force color output
 
-		if logsPattern == "" {
+		if !logsMatch.hasPattern(logsPattern) && logsWhere == "" && len(logsLevels) == 0 {
 			return fmt.Errorf("pattern is required")
 		}
 
+		if logsAllNamespaces && logsNamespace != "" {
+			return fmt.Errorf("--all-namespaces and --namespace cannot be used together")
+		}
+
+		// Without a pattern, --where and --level alone select the reported lines.
+		var matcher match.Matcher
+		var err error
+		if logsMatch.hasPattern(logsPattern) {
+			matcher, err = logsMatch.newMatcher(logsPattern)
+			if err != nil {
+				return err
+			}
+		}
+
+		before, after, err := logsContext.lines(cmd)
+		if err != nil {
+			return err
+		}
+
+		if err := validateConcurrency(logsConcurrency); err != nil {
+			return err
+		}
+
+		if err := validateChunkSize(logsChunkSize); err != nil {
+			return err
+		}
+
+		if logsMaxLineLength < 1 {
+			return fmt.Errorf("invalid max line length %d: must be at least 1", logsMaxLineLength)
+		}
+
+		options := log.Options{
+			BeforeContext: before,
+			AfterContext:  after,
+			Concurrency:   logsConcurrency,
+			ChunkSize:     logsChunkSize,
+			MaxLineLength: logsMaxLineLength,
+			OnError:       printWarning,
+		}
+		if err := logsWindow.apply(&options); err != nil {
+			return err
+		}
+
+		if err := logsPods.apply(&options); err != nil {
+			return err
+		}
+
+		if logsWhere != "" {
+			// Compiling the expression first reports mistakes before any API call.
+			options.Where, err = predicate.NewFields(logsWhere)
+			if err != nil {
+				return err
+			}
+		}
+
+		fields, err := parseFieldNames(logsFields)
+		if err != nil {
+			return err
+		}
+		options.Structured = len(fields) > 0
+
+		options.Levels, err = parseLevels(logsLevels)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case logsPrevious && logsIncludePrevious:
+			return fmt.Errorf("--previous and --include-previous cannot be used together")
+		case logsPrevious:
+			options.Instances = log.InstancesPrevious
+		case logsIncludePrevious:
+			options.Instances = log.InstancesAll
+		}
+
+		options.Containers, options.ContainerTypes, err = parseContainerFilters(logsContainers, logsContainerTypes)
+		if err != nil {
+			return err
+		}
+
+		if logsFollow {
+			switch {
+			case logsPrevious || logsIncludePrevious:
+				return fmt.Errorf("--follow cannot be used with --previous or --include-previous")
+			case logsWindow.until != "":
+				return fmt.Errorf("--follow cannot be used with --until")
+			case cmd.Flags().Changed("sort-by") && log.NeedsSort(logsSortBy):
+				return fmt.Errorf("--follow cannot be used with --sort-by %s: lines are printed as they're written", logsSortBy)
+			}
+		}
+
+		resource := logsResource
+		if logsPodNameContains != "" {
+			if logsResource != "" {
+				return fmt.Errorf("--resource and --pod-name-contains cannot be used together")
+			}
+			resource = logsPodNameContains
+			options.PodNameContains = true
+		}
+
 		grepper, err := log.NewLogGrepper()
 		if err != nil {
 			return fmt.Errorf("failed to create log grepper: %v", err)
 		}
+		grepper.SetOptions(options)
+
+		printer := newMessagePrinter(logsMatch.multiplePatterns(logsPattern), logsContext.enabled(), logsTimestamps)
+		printer.showNamespaces = logsAllNamespaces
+		printer.fields = fields
+
+		if logsFollow {
+			// Lines of different pods are interleaved, so each pod gets its own colour.
+			printer.podColors = map[string]*color.Color{}
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			if logsAllNamespaces {
+				err = grepper.FollowAllNamespaces(ctx, resource, matcher, printer.print)
+			} else if logsNamespace != "" {
+				err = grepper.Follow(ctx, logsNamespace, resource, matcher, printer.print)
+			} else {
+				err = grepper.FollowWithoutNamespace(ctx, resource, matcher, printer.print)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to follow logs: %v", err)
+			}
+			return nil
+		}
+
+		// Without a sort, messages are printed as they're found.
+		if !log.NeedsSort(logsSortBy) {
+			if logsAllNamespaces {
+				err = grepper.GrepAllNamespacesStream(resource, matcher, printer.print)
+			} else if logsNamespace != "" {
+				err = grepper.GrepStream(logsNamespace, resource, matcher, printer.print)
+			} else {
+				err = grepper.GrepWithoutNamespaceStream(resource, matcher, printer.print)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to search logs: %v", err)
+			}
+			return nil
+		}
 
 		var messages []log.Message
 
-		if logsNamespace != "" {
-			if logsResource != "" {
-				messages, err = grepper.Grep(logsNamespace, logsResource, logsPattern, logsSortBy)
+		if logsAllNamespaces {
+			messages, err = grepper.GrepAllNamespaces(resource, matcher, logsSortBy)
+			if err != nil {
+				return fmt.Errorf("failed to search logs: %v", err)
+			}
+		} else if logsNamespace != "" {
+			if resource != "" {
+				messages, err = grepper.Grep(logsNamespace, resource, matcher, logsSortBy)
 				if err != nil {
 					return fmt.Errorf("failed to search logs: %v", err)
 				}
 			} else {
-				messages, err = grepper.GrepNamespace(logsNamespace, logsPattern, logsSortBy)
+				messages, err = grepper.GrepNamespace(logsNamespace, matcher, logsSortBy)
 				if err != nil {
 					return fmt.Errorf("failed to search logs: %v", err)
 				}
 			}
 		} else {
-			if logsResource != "" {
-				messages, err = grepper.GrepResourceWithoutNamespace(logsResource, logsPattern, logsSortBy)
+			if resource != "" {
+				messages, err = grepper.GrepResourceWithoutNamespace(resource, matcher, logsSortBy)
 				if err != nil {
 					return fmt.Errorf("failed to search logs: %v", err)
 				}
 			} else {
-				messages, err = grepper.GrepWithoutNamespace(logsPattern, logsSortBy)
+				messages, err = grepper.GrepWithoutNamespace(matcher, logsSortBy)
 				if err != nil {
 					return fmt.Errorf("failed to search logs: %v", err)
 				}
 			}
 		}
 
-		printLogMessages(messages, logsPattern)
+		for _, message := range messages {
+			printer.print(message)
+		}
 
 		return nil
 	},
@@ -72,25 +246,257 @@ func init() {
 	rootCmd.AddCommand(logsCmd)
 
 	logsCmd.Flags().StringVarP(&logsNamespace, "namespace", "n", "", "The Kubernetes namespace")
-	logsCmd.Flags().StringVarP(&logsResource, "resource", "r", "", "The Kubernetes resource name")
+	logsCmd.Flags().StringVarP(&logsResource, "resource", "r", "", "Search the pods of a resource: a pod name, or kind/name, e.g. deployment/api; kinds are pod, deployment, statefulset, daemonset, replicaset, job, cronjob and service")
+	logsCmd.Flags().StringVar(&logsPodNameContains, "pod-name-contains", "", "Search the pods whose name contains a string")
+	addPodSelectorFlags(logsCmd, &logsPods)
+	logsCmd.Flags().BoolVarP(&logsAllNamespaces, "all-namespaces", "A", false, "If present, search the logs of pods across all namespaces")
 	logsCmd.Flags().StringVarP(&logsPattern, "pattern", "p", "", "grep search pattern")
-	logsCmd.Flags().StringVarP(&logsSortBy, "sort-by", "s", "timestamp", "Sort by: timestamp, message")
+	logsCmd.Flags().StringVarP(&logsSortBy, "sort-by", "s", "timestamp", "Sort by: timestamp, message, pod_and_container, none; none prints messages as they're found")
+	logsCmd.Flags().BoolVar(&logsTimestamps, "timestamps", false, "Print the timestamp of each log line")
+	logsCmd.Flags().BoolVarP(&logsFollow, "follow", "f", false, "Keep searching logs as they're written, including the logs of new pods, until interrupted")
+	addWindowFlags(logsCmd, &logsWindow)
+	logsCmd.Flags().BoolVar(&logsPrevious, "previous", false, "Search the logs of the previous instance of containers that restarted, e.g. after a crash")
+	logsCmd.Flags().StringArrayVarP(&logsContainers, "container", "c", nil, "Only search containers whose name matches a glob pattern, e.g. istio-*; may be repeated")
+	logsCmd.Flags().StringSliceVar(&logsContainerTypes, "container-type", nil, "Only search containers of the given types: regular, init, sidecar, ephemeral")
+	logsCmd.Flags().BoolVar(&logsIncludePrevious, "include-previous", false, "Search the logs of the previous instance of containers that restarted, along with the current ones")
+	addMatchFlags(logsCmd, &logsMatch)
+	addContextFlags(logsCmd, &logsContext)
+	addConcurrencyFlag(logsCmd, &logsConcurrency)
+	addChunkSizeFlag(logsCmd, &logsChunkSize)
+	logsCmd.Flags().IntVar(&logsMaxLineLength, "max-line-length", log.DefaultMaxLineLength, "Truncate log lines longer than this many bytes")
+	logsCmd.Flags().StringVar(&logsWhere, "where", "", "Only report JSON or logfmt lines whose fields satisfy a CEL expression, e.g. 'level == \"error\" && latency_ms > 500'")
+	logsCmd.Flags().StringSliceVar(&logsFields, "fields", nil, "Only print the given fields of JSON or logfmt lines, e.g. msg,trace_id; nested fields are separated by dots")
+	logsCmd.Flags().StringSliceVar(&logsLevels, "level", nil, "Only report lines of the given levels: trace, debug, info, warn, error, fatal; append + to include higher levels, e.g. warn+")
+
+	logsCmd.MarkFlagsOneRequired("pattern", "regexp", "pattern-file", "where", "level")
+}
 
-	if err := logsCmd.MarkFlagRequired("pattern"); err != nil {
-		panic(fmt.Sprintf("failed to mark pattern flag as required: %v", err))
+// messagePrinter prints log messages with their context lines.
+type messagePrinter struct {
+	showPatterns   bool
+	showTimestamps bool
+	// showNamespaces prefixes pod names with their namespace.
+	showNamespaces bool
+	groups         contextGroups
+	// podColors holds the colour of each pod printed, if pods are coloured.
+	podColors map[string]*color.Color
+	// fields lists the fields printed for structured lines, if projected.
+	fields []string
+}
+
+// podPalette is the colours pods are printed in, in order of appearance.
+var podPalette = []color.Attribute{
+	color.FgCyan, color.FgGreen, color.FgYellow, color.FgBlue,
+	color.FgHiCyan, color.FgHiGreen, color.FgHiYellow, color.FgHiBlue,
+}
+
+func newMessagePrinter(showPatterns bool, withContext bool, showTimestamps bool) *messagePrinter {
+	return &messagePrinter{
+		showPatterns:   showPatterns,
+		showTimestamps: showTimestamps,
+		groups:         contextGroups{enabled: withContext},
+	}
+}
+
+// timestampLayout is RFC 3339 in UTC with a fixed number of fractional digits,
+// so that the timestamp column has a constant width.
+const timestampLayout = "2006-01-02T15:04:05.000000000Z"
+
+// timestamp renders the timestamp column, if enabled. Lines without a
+// timestamp are padded to keep messages aligned.
+func (p *messagePrinter) timestamp(timestamp time.Time) string {
+	if !p.showTimestamps {
+		return ""
+	}
+	if timestamp.IsZero() {
+		return strings.Repeat(" ", len(timestampLayout)+1)
 	}
+	return color.MagentaString("%s", timestamp.UTC().Format(timestampLayout)) + " "
 }
 
-func printLogMessages(messages []log.Message, pattern string) {
-	if len(messages) == 0 {
-		return
+// parseContainerFilters validates the container name patterns and types to search.
+func parseContainerFilters(patterns []string, types []string) ([]string, []log.ContainerType, error) {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, nil, fmt.Errorf("invalid container pattern %q: %v", pattern, err)
+		}
+	}
+
+	var containerTypes []log.ContainerType
+	for _, t := range types {
+		containerType := log.ContainerType(strings.ToLower(strings.TrimSpace(t)))
+		if !slices.Contains(log.ContainerTypes, containerType) {
+			return nil, nil, fmt.Errorf("invalid container type %q: must be one of: regular, init, sidecar, ephemeral", t)
+		}
+		containerTypes = append(containerTypes, containerType)
 	}
 
-	boldRed := color.New(color.FgRed).Add(color.Bold)
+	return patterns, containerTypes, nil
+}
+
+// messageSource identifies the container instance that logged a message, e.g.
+// pod/container for regular containers or pod/container(init,previous),
+// optionally prefixed with the namespace.
+func messageSource(message log.Message, withNamespace bool) string {
+	source := message.PodName + "/" + message.ContainerName
+	if withNamespace {
+		source = message.Namespace + "/" + source
+	}
 
-	for _, message := range messages {
-		highlightedMessage := strings.ReplaceAll(message.Message, pattern, boldRed.Sprint(pattern))
-		prefix := color.BlueString("%s/%s[%d]:", message.PodName, message.ContainerName, message.LineNumber)
-		fmt.Printf("%s %s\n", prefix, highlightedMessage)
+	var tags []string
+	if message.ContainerType != "" && message.ContainerType != log.ContainerTypeRegular {
+		tags = append(tags, string(message.ContainerType))
+	}
+	if message.Previous {
+		tags = append(tags, "previous")
+	}
+	if len(tags) > 0 {
+		source += "(" + strings.Join(tags, ",") + ")"
+	}
+	return source
+}
+
+// sourceColor returns the colour the source of a message is printed in.
+func (p *messagePrinter) sourceColor(message log.Message) *color.Color {
+	if p.podColors == nil {
+		return color.New(color.FgBlue)
+	}
+
+	pod := message.Namespace + "/" + message.PodName
+	c, found := p.podColors[pod]
+	if !found {
+		c = color.New(podPalette[len(p.podColors)%len(podPalette)])
+		p.podColors[pod] = c
+	}
+	return c
+}
+
+func (p *messagePrinter) print(message log.Message) {
+	source := messageSource(message, p.showNamespaces)
+	sourceColor := p.sourceColor(message)
+
+	firstLine := message.LineNumber
+	if len(message.Before) > 0 {
+		firstLine = message.Before[0].LineNumber
+	}
+	p.groups.start(source, firstLine)
+
+	for _, line := range message.Before {
+		printContextLine(p.timestamp(line.Timestamp)+sourceColor.Sprintf("%s[%d]-", source, line.LineNumber), line.Message)
+	}
+
+	highlightedMessage := highlight(message.Message, message.Matches, levelColor(message.Level))
+	if len(p.fields) > 0 && message.Fields != nil {
+		highlightedMessage = projectFields(message.Fields, p.fields)
+	}
+	prefix := p.timestamp(message.Timestamp) + sourceColor.Sprintf("%s[%d]:", source, message.LineNumber)
+	fmt.Printf("%s %s%s\n", prefix, highlightedMessage, matchedPatterns(message.Patterns, p.showPatterns))
+
+	for _, line := range message.After {
+		printContextLine(p.timestamp(line.Timestamp)+sourceColor.Sprintf("%s[%d]-", source, line.LineNumber), line.Message)
+	}
+
+	p.groups.end(message.LineNumber + len(message.After))
+}
+
+// parseLevels parses the levels of the lines to report. A level followed by +
+// selects it and the higher levels.
+func parseLevels(names []string) ([]log.Level, error) {
+	var levels []log.Level
+	for _, name := range names {
+		base, orHigher := strings.CutSuffix(strings.TrimSpace(name), "+")
+		level, ok := log.ParseLevel(base)
+		if !ok {
+			return nil, fmt.Errorf("invalid level %q: must be one of: trace, debug, info, warn, error, fatal, optionally followed by +", name)
+		}
+
+		for _, l := range log.Levels {
+			if (l == level || (orHigher && l > level)) && !slices.Contains(levels, l) {
+				levels = append(levels, l)
+			}
+		}
+	}
+	return levels, nil
+}
+
+// levelColor returns the colour of the lines logged at a level, or nil for
+// lines printed in the default colour.
+func levelColor(level log.Level) *color.Color {
+	switch level {
+	case log.LevelFatal:
+		return color.New(color.FgHiRed, color.Bold)
+	case log.LevelError:
+		return color.New(color.FgHiRed)
+	case log.LevelWarn:
+		return color.New(color.FgYellow)
+	case log.LevelDebug, log.LevelTrace:
+		return color.New(color.Faint)
+	}
+	return nil
+}
+
+// parseFieldNames validates the fields to print for structured lines.
+func parseFieldNames(names []string) ([]string, error) {
+	var fields []string
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || strings.HasPrefix(name, ".") || strings.HasSuffix(name, ".") || strings.Contains(name, "..") {
+			return nil, fmt.Errorf("invalid field %q", name)
+		}
+		fields = append(fields, name)
+	}
+	return fields, nil
+}
+
+// projectFields renders the given fields of a structured line in logfmt, in
+// the order given. Missing fields are left out.
+func projectFields(values map[string]interface{}, fields []string) string {
+	var pairs []string
+	for _, field := range fields {
+		value, found := fieldValue(values, field)
+		if !found {
+			continue
+		}
+		pairs = append(pairs, field+"="+formatFieldValue(value))
+	}
+	return strings.Join(pairs, " ")
+}
+
+// fieldValue looks up a field, where dots separate the keys of nested
+// objects unless the field itself contains dots, like http.status in logfmt.
+func fieldValue(values map[string]interface{}, field string) (interface{}, bool) {
+	if value, found := values[field]; found {
+		return value, true
+	}
+
+	key, rest, nested := strings.Cut(field, ".")
+	if !nested {
+		return nil, false
+	}
+	object, ok := values[key].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	return fieldValue(object, rest)
+}
+
+// formatFieldValue renders a field value in logfmt. Strings are quoted when
+// needed, and objects and arrays are rendered as JSON.
+func formatFieldValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		if v == "" || strings.ContainsAny(v, " \t\"=\\") || strings.ContainsFunc(v, func(r rune) bool { return r < ' ' }) {
+			return fmt.Sprintf("%q", v)
+		}
+		return v
+	case nil:
+		return "null"
+	case map[string]interface{}, []interface{}:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(encoded)
 	}
+	return fmt.Sprint(value)
 }
@@ -11,16 +11,19 @@ var (
 	podsNamespace     string
 	podsPattern       string
 	podsAllNamespaces bool
+	podsMatch         matchFlags
+	podsSearch        searchFlags
 )
 
 var podsCmd = &cobra.Command{
-	Use:   "pods",
+	Use:   "pods [flags] [NAME...]",
 	Short: "Search Pods in Kubernetes",
-	Long:  `Search the content of Pods for specific patterns within designated namespaces.`,
+	Long: `Search the content of Pods for specific patterns within designated namespaces.
+Optionally restrict the search to the named objects; names may be glob patterns like web-*.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// For runtime errors, we don't want to show usage
 		cmd.SilenceUsage = true
-		if podsPattern == "" {
+		if !podsMatch.hasPattern(podsPattern) {
 			return fmt.Errorf("pattern is required")
 		}
 
@@ -28,30 +31,41 @@ var podsCmd = &cobra.Command{
 			return fmt.Errorf("--all-namespaces and --namespace cannot be used together")
 		}
 
+		matcher, err := podsMatch.newMatcher(podsPattern)
+		if err != nil {
+			return err
+		}
+
+		options, err := podsSearch.options(cmd, args)
+		if err != nil {
+			return err
+		}
+
 		resourceSearcher, err := resource.NewResourceSearcher("pods")
 		if err != nil {
 			return fmt.Errorf("failed to create resource searcher: %v", err)
 		}
+		resourceSearcher.SetOptions(options)
 
-		var occurrences []resource.Occurrence
+		printer := newOccurrencePrinter(podsMatch.description(podsPattern), podsMatch.multiplePatterns(podsPattern), podsSearch.enabled())
 		if podsAllNamespaces {
-			occurrences, err = resourceSearcher.SearchAllNamespaces(podsPattern)
+			err = resourceSearcher.SearchAllNamespacesStream(matcher, printer.print)
 			if err != nil {
 				return fmt.Errorf("failed to search pods: %v", err)
 			}
 		} else if podsNamespace != "" {
-			occurrences, err = resourceSearcher.Search(podsNamespace, podsPattern)
+			err = resourceSearcher.SearchStream(podsNamespace, matcher, printer.print)
 			if err != nil {
 				return fmt.Errorf("failed to search pods: %v", err)
 			}
 		} else {
-			occurrences, err = resourceSearcher.SearchWithoutNamespace(podsPattern)
+			err = resourceSearcher.SearchWithoutNamespaceStream(matcher, printer.print)
 			if err != nil {
 				return fmt.Errorf("failed to search pods: %v", err)
 			}
 		}
 
-		printResourceOccurrences(occurrences, podsPattern)
+		printer.finish()
 
 		return nil
 	},
@@ -63,8 +77,8 @@ func init() {
 	podsCmd.Flags().StringVarP(&podsNamespace, "namespace", "n", "", "The Kubernetes namespace")
 	podsCmd.Flags().StringVarP(&podsPattern, "pattern", "p", "", "grep search pattern")
 	podsCmd.Flags().BoolVarP(&podsAllNamespaces, "all-namespaces", "A", false, "If present, list the requested object(s) across all namespaces")
+	addMatchFlags(podsCmd, &podsMatch)
+	addSearchFlags(podsCmd, &podsSearch)
 
-	if err := podsCmd.MarkFlagRequired("pattern"); err != nil {
-		panic(fmt.Sprintf("failed to mark pattern flag as required: %v", err))
-	}
+	podsCmd.MarkFlagsOneRequired("pattern", "regexp", "pattern-file")
 }
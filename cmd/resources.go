@@ -3,6 +3,8 @@ package cmd
 import (
 	"fmt"
 
+	"github.com/hbelmiro/kgrep/internal/match"
+	"github.com/hbelmiro/kgrep/internal/predicate"
 	"github.com/hbelmiro/kgrep/internal/resource"
 	"github.com/spf13/cobra"
 )
@@ -13,12 +15,16 @@ var (
 	resourcesAPIVersion    string
 	resourcesKind          string
 	resourcesAllNamespaces bool
+	resourcesWhere         string
+	resourcesMatch         matchFlags
+	resourcesSearch        searchFlags
 )
 
 var resourcesCmd = &cobra.Command{
-	Use:   "resources",
+	Use:   "resources [flags] [NAME...]",
 	Short: "Search Generic Resources in Kubernetes",
-	Long:  `Search the content of any Kubernetes resource for specific patterns within designated namespaces.`,
+	Long: `Search the content of any Kubernetes resource for specific patterns within designated namespaces.
+Optionally restrict the search to the named objects; names may be glob patterns like web-*.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// For runtime errors, we don't want to show usage
 		cmd.SilenceUsage = true
@@ -27,8 +33,30 @@ var resourcesCmd = &cobra.Command{
 			return fmt.Errorf("--all-namespaces and --namespace cannot be used together")
 		}
 
+		// Without a pattern, --where alone selects the reported resources.
+		var matcher match.Matcher
+		if resourcesMatch.hasPattern(resourcesPattern) {
+			var err error
+			matcher, err = resourcesMatch.newMatcher(resourcesPattern)
+			if err != nil {
+				return err
+			}
+		}
+
+		options, err := resourcesSearch.options(cmd, args)
+		if err != nil {
+			return err
+		}
+
+		if resourcesWhere != "" {
+			// Compiling the expression first reports mistakes before any API call.
+			options.Where, err = predicate.New(resourcesWhere)
+			if err != nil {
+				return err
+			}
+		}
+
 		var resourceSearcher *resource.Searcher
-		var err error
 
 		if resourcesAPIVersion != "" {
 			resourceSearcher, err = resource.NewGenericResourceSearcher(resourcesAPIVersion, resourcesKind)
@@ -41,26 +69,35 @@ var resourcesCmd = &cobra.Command{
 				return fmt.Errorf("failed to create auto-discovery resource searcher: %v", err)
 			}
 		}
+		resourceSearcher.SetOptions(options)
 
-		var occurrences []resource.Occurrence
+		description := resourcesMatch.description(resourcesPattern)
+		if resourcesWhere != "" {
+			if description != "" {
+				description += "' where '"
+			}
+			description += resourcesWhere
+		}
+
+		printer := newOccurrencePrinter(description, resourcesMatch.multiplePatterns(resourcesPattern), resourcesSearch.enabled())
 		if resourcesAllNamespaces {
-			occurrences, err = resourceSearcher.SearchAllNamespaces(resourcesPattern)
+			err = resourceSearcher.SearchAllNamespacesStream(matcher, printer.print)
 			if err != nil {
 				return fmt.Errorf("failed to search resources: %v", err)
 			}
 		} else if resourcesNamespace != "" {
-			occurrences, err = resourceSearcher.Search(resourcesNamespace, resourcesPattern)
+			err = resourceSearcher.SearchStream(resourcesNamespace, matcher, printer.print)
 			if err != nil {
 				return fmt.Errorf("failed to search resources: %v", err)
 			}
 		} else {
-			occurrences, err = resourceSearcher.SearchWithoutNamespace(resourcesPattern)
+			err = resourceSearcher.SearchWithoutNamespaceStream(matcher, printer.print)
 			if err != nil {
 				return fmt.Errorf("failed to search resources: %v", err)
 			}
 		}
 
-		printResourceOccurrences(occurrences, resourcesPattern)
+		printer.finish()
 
 		return nil
 	},
@@ -74,10 +111,11 @@ func init() {
 	resourcesCmd.Flags().StringVar(&resourcesAPIVersion, "api-version", "", "API version (e.g., v1, apps/v1). If not provided, will be auto-discovered.")
 	resourcesCmd.Flags().StringVarP(&resourcesKind, "kind", "k", "", "Resource kind (e.g., Pod, Deployment)")
 	resourcesCmd.Flags().BoolVarP(&resourcesAllNamespaces, "all-namespaces", "A", false, "If present, list the requested object(s) across all namespaces")
+	resourcesCmd.Flags().StringVar(&resourcesWhere, "where", "", "Only search resources satisfying a CEL expression on the object, e.g. 'object.spec.replicas > 3'")
+	addMatchFlags(resourcesCmd, &resourcesMatch)
+	addSearchFlags(resourcesCmd, &resourcesSearch)
 
-	if err := resourcesCmd.MarkFlagRequired("pattern"); err != nil {
-		panic(fmt.Sprintf("failed to mark pattern flag as required: %v", err))
-	}
+	resourcesCmd.MarkFlagsOneRequired("pattern", "regexp", "pattern-file", "where")
 	if err := resourcesCmd.MarkFlagRequired("kind"); err != nil {
 		panic(fmt.Sprintf("failed to mark kind flag as required: %v", err))
 	}
@@ -12,18 +12,21 @@ var (
 	secretsNamespace     string
 	secretsPattern       string
 	secretsAllNamespaces bool
+	secretsMatch         matchFlags
+	secretsSearch        searchFlags
 )
 
 var secretsCmd = &cobra.Command{
-	Use:   "secrets",
+	Use:   "secrets [flags] [NAME...]",
 	Short: "Search Secrets in Kubernetes",
-	Long:  `Search the content of Secrets for specific patterns within designated namespaces.`,
+	Long: `Search the content of Secrets for specific patterns within designated namespaces.
+Optionally restrict the search to the named objects; names may be glob patterns like web-*.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// For runtime errors, we don't want to show usage
 		cmd.SilenceUsage = true
 		color.NoColor = false // Force color output
 
-		if secretsPattern == "" {
+		if !secretsMatch.hasPattern(secretsPattern) {
 			return fmt.Errorf("pattern is required")
 		}
 
@@ -31,30 +34,41 @@ var secretsCmd = &cobra.Command{
 			return fmt.Errorf("--all-namespaces and --namespace cannot be used together")
 		}
 
+		matcher, err := secretsMatch.newMatcher(secretsPattern)
+		if err != nil {
+			return err
+		}
+
+		options, err := secretsSearch.options(cmd, args)
+		if err != nil {
+			return err
+		}
+
 		resourceSearcher, err := resource.NewResourceSearcher("secrets")
 		if err != nil {
 			return fmt.Errorf("failed to create resource searcher: %v", err)
 		}
+		resourceSearcher.SetOptions(options)
 
-		var occurrences []resource.Occurrence
+		printer := newOccurrencePrinter(secretsMatch.description(secretsPattern), secretsMatch.multiplePatterns(secretsPattern), secretsSearch.enabled())
 		if secretsAllNamespaces {
-			occurrences, err = resourceSearcher.SearchAllNamespaces(secretsPattern)
+			err = resourceSearcher.SearchAllNamespacesStream(matcher, printer.print)
 			if err != nil {
 				return fmt.Errorf("failed to search secrets: %v", err)
 			}
 		} else if secretsNamespace != "" {
-			occurrences, err = resourceSearcher.Search(secretsNamespace, secretsPattern)
+			err = resourceSearcher.SearchStream(secretsNamespace, matcher, printer.print)
 			if err != nil {
 				return fmt.Errorf("failed to search secrets: %v", err)
 			}
 		} else {
-			occurrences, err = resourceSearcher.SearchWithoutNamespace(secretsPattern)
+			err = resourceSearcher.SearchWithoutNamespaceStream(matcher, printer.print)
 			if err != nil {
 				return fmt.Errorf("failed to search secrets: %v", err)
 			}
 		}
 
-		printResourceOccurrences(occurrences, secretsPattern)
+		printer.finish()
 
 		return nil
 	},
@@ -66,8 +80,8 @@ func init() {
 	secretsCmd.Flags().StringVarP(&secretsNamespace, "namespace", "n", "", "The Kubernetes namespace")
 	secretsCmd.Flags().StringVarP(&secretsPattern, "pattern", "p", "", "grep search pattern")
 	secretsCmd.Flags().BoolVarP(&secretsAllNamespaces, "all-namespaces", "A", false, "If present, list the requested object(s) across all namespaces")
+	addMatchFlags(secretsCmd, &secretsMatch)
+	addSearchFlags(secretsCmd, &secretsSearch)
 
-	if err := secretsCmd.MarkFlagRequired("pattern"); err != nil {
-		panic(fmt.Sprintf("failed to mark pattern flag as required: %v", err))
-	}
+	secretsCmd.MarkFlagsOneRequired("pattern", "regexp", "pattern-file")
 }
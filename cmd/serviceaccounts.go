@@ -11,16 +11,19 @@ var (
 	serviceaccountsNamespace     string
 	serviceaccountsPattern       string
 	serviceaccountsAllNamespaces bool
+	serviceaccountsMatch         matchFlags
+	serviceaccountsSearch        searchFlags
 )
 
 var serviceaccountsCmd = &cobra.Command{
-	Use:   "serviceaccounts",
+	Use:   "serviceaccounts [flags] [NAME...]",
 	Short: "Search ServiceAccounts in Kubernetes",
-	Long:  `Search the content of ServiceAccounts for specific patterns within designated namespaces.`,
+	Long: `Search the content of ServiceAccounts for specific patterns within designated namespaces.
+Optionally restrict the search to the named objects; names may be glob patterns like web-*.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// For runtime errors, we don't want to show usage
 		cmd.SilenceUsage = true
-		if serviceaccountsPattern == "" {
+		if !serviceaccountsMatch.hasPattern(serviceaccountsPattern) {
 			return fmt.Errorf("pattern is required")
 		}
 
@@ -28,30 +31,41 @@ var serviceaccountsCmd = &cobra.Command{
 			return fmt.Errorf("--all-namespaces and --namespace cannot be used together")
 		}
 
+		matcher, err := serviceaccountsMatch.newMatcher(serviceaccountsPattern)
+		if err != nil {
+			return err
+		}
+
+		options, err := serviceaccountsSearch.options(cmd, args)
+		if err != nil {
+			return err
+		}
+
 		resourceSearcher, err := resource.NewResourceSearcher("serviceaccounts")
 		if err != nil {
 			return fmt.Errorf("failed to create resource searcher: %v", err)
 		}
+		resourceSearcher.SetOptions(options)
 
-		var occurrences []resource.Occurrence
+		printer := newOccurrencePrinter(serviceaccountsMatch.description(serviceaccountsPattern), serviceaccountsMatch.multiplePatterns(serviceaccountsPattern), serviceaccountsSearch.enabled())
 		if serviceaccountsAllNamespaces {
-			occurrences, err = resourceSearcher.SearchAllNamespaces(serviceaccountsPattern)
+			err = resourceSearcher.SearchAllNamespacesStream(matcher, printer.print)
 			if err != nil {
 				return fmt.Errorf("failed to search serviceaccounts: %v", err)
 			}
 		} else if serviceaccountsNamespace != "" {
-			occurrences, err = resourceSearcher.Search(serviceaccountsNamespace, serviceaccountsPattern)
+			err = resourceSearcher.SearchStream(serviceaccountsNamespace, matcher, printer.print)
 			if err != nil {
 				return fmt.Errorf("failed to search serviceaccounts: %v", err)
 			}
 		} else {
-			occurrences, err = resourceSearcher.SearchWithoutNamespace(serviceaccountsPattern)
+			err = resourceSearcher.SearchWithoutNamespaceStream(matcher, printer.print)
 			if err != nil {
 				return fmt.Errorf("failed to search serviceaccounts: %v", err)
 			}
 		}
 
-		printResourceOccurrences(occurrences, serviceaccountsPattern)
+		printer.finish()
 
 		return nil
 	},
@@ -63,8 +77,8 @@ func init() {
 	serviceaccountsCmd.Flags().StringVarP(&serviceaccountsNamespace, "namespace", "n", "", "The Kubernetes namespace")
 	serviceaccountsCmd.Flags().StringVarP(&serviceaccountsPattern, "pattern", "p", "", "grep search pattern")
 	serviceaccountsCmd.Flags().BoolVarP(&serviceaccountsAllNamespaces, "all-namespaces", "A", false, "If present, list the requested object(s) across all namespaces")
+	addMatchFlags(serviceaccountsCmd, &serviceaccountsMatch)
+	addSearchFlags(serviceaccountsCmd, &serviceaccountsSearch)
 
-	if err := serviceaccountsCmd.MarkFlagRequired("pattern"); err != nil {
-		panic(fmt.Sprintf("failed to mark pattern flag as required: %v", err))
-	}
+	serviceaccountsCmd.MarkFlagsOneRequired("pattern", "regexp", "pattern-file")
 }
@@ -2,32 +2,150 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 	"strings"
 
 	"github.com/fatih/color"
+	"github.com/hbelmiro/kgrep/internal/match"
 	"github.com/hbelmiro/kgrep/internal/resource"
 )
 
-func printResourceOccurrences(occurrences []resource.Occurrence, pattern string) {
-	if len(occurrences) == 0 {
-		fmt.Printf("No occurrences of '%s' found.\n", pattern)
+// occurrencePrinter prints resource occurrences as they're found, followed by
+// a summary once the search is done.
+type occurrencePrinter struct {
+	pattern      string
+	showPatterns bool
+	groups       contextGroups
+	count        int
+}
+
+func newOccurrencePrinter(pattern string, showPatterns bool, withContext bool) *occurrencePrinter {
+	return &occurrencePrinter{
+		pattern:      pattern,
+		showPatterns: showPatterns,
+		groups:       contextGroups{enabled: withContext},
+	}
+}
+
+func (p *occurrencePrinter) print(occurrence resource.Occurrence) {
+	p.count++
+
+	name := occurrence.Resource
+	if occurrence.Namespace != "" {
+		name = occurrence.Namespace + "/" + occurrence.Resource
+	}
+
+	if occurrence.Line == 0 {
+		// The whole resource matched without any particular line.
+		p.groups.start(name, 0)
+		fmt.Println(color.BlueString("%s", name))
+		return
+	}
+
+	firstLine := occurrence.Line
+	if len(occurrence.Before) > 0 {
+		firstLine = occurrence.Before[0].Line
+	}
+	p.groups.start(name, firstLine)
+
+	for _, line := range occurrence.Before {
+		printContextLine(fieldPrefix(name, line.Path, "-"), line.Content)
+	}
+
+	highlightedContent := highlight(occurrence.Content, occurrence.Matches, nil)
+	prefix := fieldPrefix(name, occurrence.Path, ":")
+	fmt.Printf("%s %s%s\n", prefix, highlightedContent, matchedPatterns(occurrence.Patterns, p.showPatterns))
+
+	for _, line := range occurrence.After {
+		printContextLine(fieldPrefix(name, line.Path, "-"), line.Content)
+	}
+
+	p.groups.end(occurrence.Line + len(occurrence.After))
+}
+
+// finish prints the number of occurrences found.
+func (p *occurrencePrinter) finish() {
+	if p.count == 0 {
+		fmt.Printf("No occurrences of '%s' found.\n", p.pattern)
 		return
 	}
 
-	fmt.Printf("Found %d occurrence(s) of '%s':\n\n", len(occurrences), pattern)
+	fmt.Printf("\nFound %d occurrence(s) of '%s'.\n", p.count, p.pattern)
+}
+
+// printWarning reports an error that didn't stop a search, such as a namespace
+// that couldn't be listed.
+func printWarning(err error) {
+	fmt.Fprintf(os.Stderr, "%s %v\n", color.YellowString("Warning:"), err)
+}
+
+// fieldPrefix identifies a field of a resource, followed by ":" for matches
+// and "-" for context lines.
+func fieldPrefix(name, path, separator string) string {
+	return fmt.Sprintf("%s %s%s", color.BlueString("%s", name), color.GreenString("%s", path), separator)
+}
 
-	for _, occurrence := range occurrences {
-		boldRed := color.New(color.FgRed).Add(color.Bold)
+// contextGroups separates groups of non-contiguous lines with "--", like grep
+// does when context lines are requested.
+type contextGroups struct {
+	enabled  bool
+	printed  bool
+	source   string
+	lastLine int
+}
 
-		highlightedContent := strings.ReplaceAll(occurrence.Content, pattern, boldRed.Sprint(pattern))
+// start is called before printing the lines of source starting at firstLine.
+func (g *contextGroups) start(source string, firstLine int) {
+	if g.enabled && g.printed && (source != g.source || firstLine == 0 || firstLine > g.lastLine+1) {
+		fmt.Println(color.CyanString("--"))
+	}
+	g.printed = true
+	g.source = source
+	g.lastLine = firstLine
+}
 
-		var prefix string
-		if occurrence.Namespace != "" {
-			prefix = color.BlueString("%s/%s[%d]:", occurrence.Namespace, occurrence.Resource, occurrence.Line)
-		} else {
-			prefix = color.BlueString("%s[%d]:", occurrence.Resource, occurrence.Line)
+// end records the last line printed for the current source.
+func (g *contextGroups) end(lastLine int) {
+	g.lastLine = lastLine
+}
+
+// printContextLine prints a line surrounding a match.
+func printContextLine(prefix, content string) {
+	fmt.Printf("%s %s\n", prefix, color.New(color.Faint).Sprint(content))
+}
+
+// matchedPatterns describes which patterns matched when several patterns are searched for.
+func matchedPatterns(patterns []string, show bool) string {
+	if !show || len(patterns) == 0 {
+		return ""
+	}
+	return color.YellowString(" [%s]", strings.Join(patterns, ", "))
+}
+
+// highlight renders the matched spans of content in bold red, and the rest in
+// the base colour, if any.
+func highlight(content string, spans []match.Span, base *color.Color) string {
+	boldRed := color.New(color.FgRed).Add(color.Bold)
+	plain := func(text string) string {
+		if base == nil || text == "" {
+			return text
 		}
+		return base.Sprint(text)
+	}
 
-		fmt.Printf("%s %s\n", prefix, highlightedContent)
+	var builder strings.Builder
+	last := 0
+	for _, span := range spans {
+		if span.End <= last || span.End > len(content) {
+			continue
+		}
+		// Overlapping spans only highlight what wasn't highlighted yet.
+		start := max(span.Start, last)
+		builder.WriteString(plain(content[last:start]))
+		builder.WriteString(boldRed.Sprint(content[start:span.End]))
+		last = span.End
 	}
+	builder.WriteString(plain(content[last:]))
+
+	return builder.String()
 }
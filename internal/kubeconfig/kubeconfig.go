@@ -0,0 +1,32 @@
+// Package kubeconfig loads the configuration of the clients talking to the
+// Kubernetes API server.
+package kubeconfig
+
+import (
+	"fmt"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// QPS and Burst are the client-side rate limits of the loaded configuration.
+// They're raised above client-go's defaults so that searches making requests
+// concurrently aren't throttled.
+const (
+	QPS   = 50
+	Burst = 100
+)
+
+// Load loads the configuration from the default kubeconfig loading rules, like
+// kubectl does.
+func Load() (*rest.Config, error) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	configOverrides := &clientcmd.ConfigOverrides{}
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, configOverrides).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("error creating Kubernetes config: %v", err)
+	}
+
+	config.QPS, config.Burst = QPS, Burst
+	return config, nil
+}
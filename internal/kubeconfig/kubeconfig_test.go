@@ -0,0 +1,35 @@
+package kubeconfig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: test
+  cluster:
+    server: https://test-cluster
+contexts:
+- name: test
+  context:
+    cluster: test
+current-context: test
+`
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	require.NoError(t, os.WriteFile(path, []byte(testKubeconfig), 0o600))
+	t.Setenv("KUBECONFIG", path)
+
+	config, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, "https://test-cluster", config.Host)
+	assert.Equal(t, float32(QPS), config.QPS)
+	assert.Equal(t, Burst, config.Burst)
+}
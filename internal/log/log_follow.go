@@ -0,0 +1,392 @@
+package log
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/hbelmiro/kgrep/internal/match"
+)
+
+// reconnectDelay is the delay before reopening a log stream that broke. It
+// doubles with each consecutive failure, up to maxReconnectDelay.
+// It's a variable so that tests can shorten it.
+var reconnectDelay = time.Second
+
+const (
+	maxReconnectDelay = 30 * time.Second
+	// maxReconnects is the number of consecutive failures after which a
+	// container log stops being followed.
+	maxReconnects = 5
+)
+
+// FollowWithoutNamespace is like Follow in the default namespace.
+func (g *Grepper) FollowWithoutNamespace(ctx context.Context, resource string, matcher match.Matcher, emit func(Message)) error {
+	namespace, err := g.getDefaultNamespace()
+	if err != nil {
+		return fmt.Errorf("error getting default namespace: %v", err)
+	}
+	return g.Follow(ctx, namespace, resource, matcher, emit)
+}
+
+// FollowAllNamespaces is like Follow across all namespaces.
+func (g *Grepper) FollowAllNamespaces(ctx context.Context, resource string, matcher match.Matcher, emit func(Message)) error {
+	return g.Follow(ctx, metav1.NamespaceAll, resource, matcher, emit)
+}
+
+// Follow searches the logs of the pods of a resource in a namespace, or of
+// all pods if resource is empty, as they're written, until ctx is done.
+//
+// Pods are watched, so that containers are followed as they start and the
+// logs of deleted pods stop being followed. Containers that were running
+// before Follow was called are searched according to Options.Since,
+// Options.SinceTime and Options.TailLines, and the ones that start later from
+// their first line. Streams that break are reopened after the last line read.
+//
+// Every container log is followed at once, so Options.Concurrency doesn't
+// apply, and neither do Options.Until and Options.Instances. Messages are
+// emitted as they're found, and emit is never called concurrently.
+func (g *Grepper) Follow(ctx context.Context, namespace, resource string, matcher match.Matcher, emit func(Message)) error {
+	if g.clientset == nil {
+		return fmt.Errorf("Kubernetes clientset not available")
+	}
+
+	filter, err := g.newPodFilter(ctx, namespace, resource, true)
+	if err != nil {
+		return fmt.Errorf("error getting pods: %v", err)
+	}
+
+	f := &follower{
+		grepper: g,
+		matcher: matcher,
+		filter:  filter,
+		emit:    emit,
+		started: time.Now(),
+		pods:    make(map[string]*followedPod),
+	}
+
+	// The selectors of the options, or those of a single source, which include
+	// them, are pushed to the API server.
+	selector, fieldSelector, err := g.podSelectors()
+	if err != nil {
+		return err
+	}
+	listNamespace := namespace
+	if len(filter.sources) == 1 {
+		source := filter.sources[0]
+		listNamespace, selector, fieldSelector = source.namespace, source.selector, source.fieldSelector
+	}
+	selectPods := func(options *metav1.ListOptions) {
+		options.LabelSelector = selector.String()
+		options.FieldSelector = fieldSelector.String()
+	}
+
+	pods := g.clientset.CoreV1().Pods(listNamespace)
+	// Clients that can't stream the initial list of a watch fall back to listing.
+	informer := cache.NewSharedIndexInformer(cache.ToListWatcherWithWatchListSemantics(&cache.ListWatch{
+		ListWithContextFunc: func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+			selectPods(&options)
+			return pods.List(ctx, options)
+		},
+		WatchFuncWithContext: func(ctx context.Context, options metav1.ListOptions) (watch.Interface, error) {
+			selectPods(&options)
+			return pods.Watch(ctx, options)
+		},
+	}, g.clientset), &corev1.Pod{}, 0, cache.Indexers{})
+	f.store = informer.GetStore()
+
+	if err := informer.SetWatchErrorHandler(func(_ *cache.Reflector, err error) {
+		if ctx.Err() != nil {
+			return
+		}
+		f.reportError(fmt.Errorf("error watching pods: %v", err))
+	}); err != nil {
+		return fmt.Errorf("error watching pods: %v", err)
+	}
+
+	_, err = informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { f.update(ctx, obj) },
+		UpdateFunc: func(_, obj interface{}) { f.update(ctx, obj) },
+		DeleteFunc: f.delete,
+	})
+	if err != nil {
+		return fmt.Errorf("error watching pods: %v", err)
+	}
+
+	informer.Run(ctx.Done())
+
+	f.stop()
+	return nil
+}
+
+// follower follows the logs of the containers of watched pods.
+type follower struct {
+	grepper *Grepper
+	matcher match.Matcher
+	// filter selects the pods of the resource followed.
+	filter *podFilter
+	// started is when following started. Containers that started before are
+	// searched according to the time window of the options.
+	started time.Time
+	store   cache.Store
+
+	emitMu sync.Mutex
+	emit   func(Message)
+
+	mu      sync.Mutex
+	pods    map[string]*followedPod
+	stopped bool
+	tails   sync.WaitGroup
+}
+
+// followedPod holds the container logs followed for a pod.
+type followedPod struct {
+	cancel context.CancelFunc
+	ctx    context.Context
+	// containerIDs holds the IDs of the container instances already followed.
+	containerIDs map[string]bool
+}
+
+// update starts following the containers of a pod that started running.
+func (f *follower) update(ctx context.Context, obj interface{}) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok || !f.filter.matches(ctx, pod) {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.stopped {
+		return
+	}
+
+	key := pod.Namespace + "/" + pod.Name
+	followed, found := f.pods[key]
+	if !found {
+		podCtx, cancel := context.WithCancel(ctx)
+		followed = &followedPod{ctx: podCtx, cancel: cancel, containerIDs: make(map[string]bool)}
+		f.pods[key] = followed
+	}
+
+	for _, container := range f.grepper.getContainers(*pod) {
+		if !f.grepper.selectsContainer(container) {
+			continue
+		}
+
+		// Logs can only be read once a container started.
+		status := containerStatus(*pod, container.name)
+		if status == nil || status.ContainerID == "" || (status.State.Running == nil && status.State.Terminated == nil) {
+			continue
+		}
+		if followed.containerIDs[status.ContainerID] {
+			continue
+		}
+		followed.containerIDs[status.ContainerID] = true
+
+		target := containerLog{pod: *pod, container: container.name, containerType: container.containerType}
+		f.tails.Add(1)
+		go func() {
+			defer f.tails.Done()
+			f.tail(followed.ctx, target, *status)
+		}()
+	}
+}
+
+// delete stops following the containers of a deleted pod.
+func (f *follower) delete(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if followed, found := f.pods[key]; found {
+		followed.cancel()
+		delete(f.pods, key)
+	}
+}
+
+// stop stops following every pod and waits for the streams to be closed.
+func (f *follower) stop() {
+	f.mu.Lock()
+	f.stopped = true
+	for _, followed := range f.pods {
+		followed.cancel()
+	}
+	f.mu.Unlock()
+
+	f.tails.Wait()
+}
+
+// tail follows the log of a container instance until ctx is done or the
+// container stops running.
+func (f *follower) tail(ctx context.Context, target containerLog, status corev1.ContainerStatus) {
+	g := f.grepper
+
+	options := &corev1.PodLogOptions{Container: target.container, Timestamps: true}
+	if startedAt(status).Before(f.started) {
+		options = g.podLogOptions(target.container, false)
+	}
+	options.Follow = true
+
+	searcher := g.newLineSearcher(f.matcher, target.pod.Name, target.container, func(message Message) {
+		message.Namespace = target.pod.Namespace
+		message.ContainerType = target.containerType
+		f.emitMu.Lock()
+		defer f.emitMu.Unlock()
+		f.emit(message)
+	})
+	defer searcher.flush()
+
+	// last is the timestamp of the last line read, where broken streams resume.
+	// While replaying, the lines a reopened stream repeats up to last are skipped.
+	var last time.Time
+	replaying := false
+	lineNumber := 1
+	failures := 0
+	for {
+		opened := time.Now()
+		logs, err := g.logReader.GetPodLogs(target.pod.Namespace, target.pod.Name, options)
+		if err == nil {
+			err = f.read(ctx, logs, func(line string, truncated bool) {
+				timestamp, line := parseTimestamp(line)
+				if replaying && !timestamp.IsZero() {
+					if !timestamp.After(last) {
+						// The line was read before the stream was reopened.
+						return
+					}
+					replaying = false
+				}
+				if truncated {
+					f.reportError(fmt.Errorf("%s: line %d longer than %d bytes was truncated", target, lineNumber, g.maxLineLength()))
+				}
+				if !timestamp.IsZero() {
+					last = timestamp
+				}
+				failures = 0
+
+				searcher.search(lineNumber, timestamp, line)
+				lineNumber++
+			})
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err == nil {
+			// The stream ended. It's reopened if the server closed it while
+			// the container is still running.
+			if !f.running(target, status.ContainerID) {
+				return
+			}
+		} else {
+			failures++
+			if failures > maxReconnects {
+				f.reportError(fmt.Errorf("%s: %v; giving up after %d attempts", target, err, failures))
+				return
+			}
+			f.reportError(fmt.Errorf("%s: %v; reconnecting", target, err))
+		}
+
+		delay := reconnectDelay << max(failures-1, 0)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(min(delay, maxReconnectDelay)):
+		}
+
+		since := last
+		if since.IsZero() {
+			since = opened
+		}
+		replaying = !last.IsZero()
+		sinceTime := metav1.NewTime(since)
+		options = &corev1.PodLogOptions{Container: target.container, Timestamps: true, Follow: true, SinceTime: &sinceTime}
+	}
+}
+
+// read reads a log stream line by line until it ends or ctx is done. io.EOF
+// isn't returned.
+func (f *follower) read(ctx context.Context, logs io.ReadCloser, readLineFunc func(line string, truncated bool)) error {
+	// Closing the stream unblocks the read in progress.
+	stop := context.AfterFunc(ctx, func() { logs.Close() })
+	defer stop()
+	defer logs.Close()
+
+	reader := bufio.NewReader(logs)
+	maxLineLength := f.grepper.maxLineLength()
+	for {
+		line, truncated, err := readLine(reader, maxLineLength)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		readLineFunc(line, truncated)
+	}
+}
+
+// running reports whether a container instance is still running, according
+// to the last known state of its pod.
+func (f *follower) running(target containerLog, containerID string) bool {
+	obj, found, err := f.store.GetByKey(target.pod.Namespace + "/" + target.pod.Name)
+	if err != nil || !found {
+		return false
+	}
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return false
+	}
+
+	status := containerStatus(*pod, target.container)
+	return status != nil && status.ContainerID == containerID && status.State.Running != nil
+}
+
+func (f *follower) reportError(err error) {
+	if f.grepper.options.OnError == nil {
+		return
+	}
+	f.emitMu.Lock()
+	defer f.emitMu.Unlock()
+	f.grepper.options.OnError(err)
+}
+
+// containerStatus returns the status of a container of a pod, or nil if it
+// has none yet.
+func containerStatus(pod corev1.Pod, container string) *corev1.ContainerStatus {
+	for _, statuses := range [][]corev1.ContainerStatus{
+		pod.Status.InitContainerStatuses,
+		pod.Status.ContainerStatuses,
+		pod.Status.EphemeralContainerStatuses,
+	} {
+		for i := range statuses {
+			if statuses[i].Name == container {
+				return &statuses[i]
+			}
+		}
+	}
+	return nil
+}
+
+// startedAt returns when a container instance started.
+func startedAt(status corev1.ContainerStatus) time.Time {
+	switch {
+	case status.State.Running != nil:
+		return status.State.Running.StartedAt.Time
+	case status.State.Terminated != nil:
+		return status.State.Terminated.StartedAt.Time
+	}
+	return time.Time{}
+}
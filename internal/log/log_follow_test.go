@@ -0,0 +1,212 @@
+package log
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// pipeLogReader serves live logs written through pipes, one per pod.
+type pipeLogReader struct {
+	mu      sync.Mutex
+	writers map[string]*io.PipeWriter
+}
+
+func (r *pipeLogReader) GetPodLogs(_, podName string, _ *corev1.PodLogOptions) (io.ReadCloser, error) {
+	reader, writer := io.Pipe()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.writers[podName] = writer
+	return reader, nil
+}
+
+// write writes a line to the log of a pod once it's followed, and reports
+// whether it was read.
+func (r *pipeLogReader) write(t *testing.T, podName, line string) bool {
+	t.Helper()
+
+	var writer *io.PipeWriter
+	require.Eventually(t, func() bool {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		writer = r.writers[podName]
+		return writer != nil
+	}, 5*time.Second, 10*time.Millisecond, "log of %s not followed", podName)
+
+	_, err := writer.Write([]byte(line + "\n"))
+	return err == nil
+}
+
+func runningPod(name, containerID string, startedAt time.Time) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test"},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+		Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+			Name:        "app",
+			ContainerID: containerID,
+			State:       corev1.ContainerState{Running: &corev1.ContainerStateRunning{StartedAt: metav1.NewTime(startedAt)}},
+		}}},
+	}
+}
+
+// follow runs Follow in the background and returns the channel messages are
+// sent to, and a function stopping it.
+func follow(t *testing.T, grepper *Grepper, pattern string) (<-chan Message, func()) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	messages := make(chan Message, 100)
+	done := make(chan error)
+	go func() {
+		done <- grepper.Follow(ctx, "test", "", newMatcher(t, pattern), func(message Message) {
+			messages <- message
+		})
+	}()
+
+	return messages, func() {
+		cancel()
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("Follow didn't stop")
+		}
+	}
+}
+
+func receive(t *testing.T, messages <-chan Message) Message {
+	t.Helper()
+	select {
+	case message := <-messages:
+		return message
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message received")
+		return Message{}
+	}
+}
+
+func TestLogGrepper_Follow(t *testing.T) {
+	clientset := fake.NewClientset(runningPod("pod1", "containerd://1", time.Now().Add(-time.Hour)))
+	reader := &pipeLogReader{writers: make(map[string]*io.PipeWriter)}
+	grepper := &Grepper{clientset: clientset, logReader: reader}
+
+	messages, stop := follow(t, grepper, "error")
+	defer stop()
+
+	require.True(t, reader.write(t, "pod1", "2024-05-01T10:00:00Z ok"))
+	require.True(t, reader.write(t, "pod1", "2024-05-01T10:00:01Z error one"))
+	message := receive(t, messages)
+	assert.Equal(t, "pod1", message.PodName)
+	assert.Equal(t, ContainerTypeRegular, message.ContainerType)
+	assert.Equal(t, "error one", message.Message)
+	assert.Equal(t, 2, message.LineNumber)
+
+	// Pods created while following are picked up by the watch.
+	require.Eventually(t, func() bool {
+		for _, action := range clientset.Actions() {
+			if action.GetVerb() == "watch" {
+				return true
+			}
+		}
+		return false
+	}, 5*time.Second, 10*time.Millisecond)
+	_, err := clientset.CoreV1().Pods("test").Create(context.Background(), runningPod("pod2", "containerd://2", time.Now()), metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	require.True(t, reader.write(t, "pod2", "2024-05-01T10:00:02Z error two"))
+	message = receive(t, messages)
+	assert.Equal(t, "pod2", message.PodName)
+	assert.Equal(t, "error two", message.Message)
+
+	// Deleted pods stop being followed.
+	err = clientset.CoreV1().Pods("test").Delete(context.Background(), "pod1", metav1.DeleteOptions{})
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		return !reader.write(t, "pod1", "2024-05-01T10:00:03Z error three")
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestLogGrepper_Follow_RepeatedTimestamps(t *testing.T) {
+	reader := &pipeLogReader{writers: make(map[string]*io.PipeWriter)}
+	grepper := &Grepper{
+		clientset: fake.NewClientset(runningPod("pod1", "containerd://1", time.Now().Add(-time.Hour))),
+		logReader: reader,
+	}
+
+	messages, stop := follow(t, grepper, "error")
+	defer stop()
+
+	// Lines logged in the same instant or without a timestamp aren't dropped.
+	require.True(t, reader.write(t, "pod1", "2024-05-01T10:00:01Z error one"))
+	require.True(t, reader.write(t, "pod1", "2024-05-01T10:00:01Z error two"))
+	require.True(t, reader.write(t, "pod1", "error three"))
+	require.True(t, reader.write(t, "pod1", "2024-05-01T10:00:00Z error four"))
+
+	for i, expected := range []string{"error one", "error two", "error three", "error four"} {
+		message := receive(t, messages)
+		assert.Equal(t, expected, message.Message)
+		assert.Equal(t, i+1, message.LineNumber)
+	}
+}
+
+func TestLogGrepper_Follow_Reconnects(t *testing.T) {
+	defer func(delay time.Duration) { reconnectDelay = delay }(reconnectDelay)
+	reconnectDelay = time.Millisecond
+
+	var mu sync.Mutex
+	var requests []*corev1.PodLogOptions
+	live, writer := io.Pipe()
+	defer writer.Close()
+
+	var errs []error
+	grepper := &Grepper{
+		clientset: fake.NewClientset(runningPod("pod1", "containerd://1", time.Now().Add(-time.Hour))),
+		logReader: readerFunc(func(_, _ string, options *corev1.PodLogOptions) (io.ReadCloser, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			requests = append(requests, options)
+			if len(requests) == 1 {
+				return io.NopCloser(&abortingReader{content: strings.NewReader("2024-05-01T10:00:01.5Z error one\n")}), nil
+			}
+			// The reopened stream repeats the lines logged during the second of the last line read.
+			return struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(strings.NewReader("2024-05-01T10:00:01.5Z error one\n2024-05-01T10:00:02Z error two\n"), live), live}, nil
+		}),
+		options: Options{TailLines: 10, OnError: func(err error) { errs = append(errs, err) }},
+	}
+
+	messages, stop := follow(t, grepper, "error")
+
+	message := receive(t, messages)
+	assert.Equal(t, "error one", message.Message)
+	assert.Equal(t, 1, message.LineNumber)
+
+	message = receive(t, messages)
+	assert.Equal(t, "error two", message.Message)
+	assert.Equal(t, 2, message.LineNumber, "line numbers continue across reconnections")
+
+	stop()
+
+	require.Len(t, requests, 2)
+	assert.True(t, requests[0].Follow)
+	assert.Equal(t, int64(10), *requests[0].TailLines)
+	assert.True(t, requests[1].Follow)
+	assert.Nil(t, requests[1].TailLines)
+	require.NotNil(t, requests[1].SinceTime)
+	assert.Equal(t, time.Date(2024, 5, 1, 10, 0, 1, 500000000, time.UTC), requests[1].SinceTime.Time)
+
+	require.Len(t, errs, 1)
+	assert.Equal(t, "pod test/pod1 container app: connection reset by peer; reconnecting", errs[0].Error())
+	assert.Empty(t, messages)
+}
@@ -4,7 +4,12 @@ import (
 	"bufio"
 	"context"
 	"fmt"
+	"io"
+	"math"
+	"path"
+	"slices"
 	"strings"
+	"time"
 
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/clientcmd"
@@ -12,12 +17,21 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/rest"
+
+	"github.com/hbelmiro/kgrep/internal/kubeconfig"
+	"github.com/hbelmiro/kgrep/internal/match"
+	"github.com/hbelmiro/kgrep/internal/pager"
+	"github.com/hbelmiro/kgrep/internal/predicate"
+	"github.com/hbelmiro/kgrep/internal/worker"
 )
 
-// LogReader is an interface for reading logs from a pod.
+// Reader is an interface for reading logs from a pod.
 // This allows for swapping a fake implementation during testing.
 type Reader interface {
-	GetPodLogs(namespace, podName, containerName string) (string, error)
+	// GetPodLogs opens a stream of the logs of a pod, as selected by options.
+	// Lines may be prefixed with an RFC 3339 timestamp, which is stripped from
+	// messages. The caller must close the stream.
+	GetPodLogs(namespace, podName string, options *corev1.PodLogOptions) (io.ReadCloser, error)
 }
 
 // DefaultLogReader is the production implementation of LogReader.
@@ -26,17 +40,10 @@ type DefaultLogReader struct {
 	clientset kubernetes.Interface
 }
 
-func (r *DefaultLogReader) GetPodLogs(namespace, podName, containerName string) (string, error) {
-	req := r.clientset.CoreV1().Pods(namespace).GetLogs(podName, &corev1.PodLogOptions{
-		Container: containerName,
-	})
-
-	logs, err := req.Do(context.Background()).Raw()
-	if err != nil {
-		return "", err
-	}
-
-	return string(logs), nil
+// GetPodLogs streams the logs of a pod, so that they are searched as they are
+// read instead of being loaded into memory at once.
+func (r *DefaultLogReader) GetPodLogs(namespace, podName string, options *corev1.PodLogOptions) (io.ReadCloser, error) {
+	return r.clientset.CoreV1().Pods(namespace).GetLogs(podName, options).Stream(context.Background())
 }
 
 // Grepper searches and filters logs from Kubernetes pods.
@@ -44,15 +51,19 @@ type Grepper struct {
 	clientset kubernetes.Interface
 	config    *rest.Config
 	logReader Reader
+	options   Options
+}
+
+// SetOptions configures optional search behavior.
+func (g *Grepper) SetOptions(options Options) {
+	g.options = options
 }
 
 // NewLogGrepper creates a new LogGrepper with a default configuration.
 func NewLogGrepper() (*Grepper, error) {
-	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
-	configOverrides := &clientcmd.ConfigOverrides{}
-	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, configOverrides).ClientConfig()
+	config, err := kubeconfig.Load()
 	if err != nil {
-		return nil, fmt.Errorf("error creating Kubernetes config: %v", err)
+		return nil, err
 	}
 
 	clientset, err := kubernetes.NewForConfig(config)
@@ -68,46 +79,90 @@ func NewLogGrepper() (*Grepper, error) {
 }
 
 // GrepWithoutNamespace searches for a pattern in logs across all pods in the default namespace.
-func (g *Grepper) GrepWithoutNamespace(pattern, sortBy string) ([]Message, error) {
+func (g *Grepper) GrepWithoutNamespace(matcher match.Matcher, sortBy string) ([]Message, error) {
 	namespace, err := g.getDefaultNamespace()
 	if err != nil {
 		return nil, fmt.Errorf("error getting default namespace: %v", err)
 	}
-	return g.Grep(namespace, "", pattern, sortBy)
+	return g.Grep(namespace, "", matcher, sortBy)
 }
 
 // GrepResourceWithoutNamespace searches for a pattern in the logs of a specific resource in the default namespace.
-func (g *Grepper) GrepResourceWithoutNamespace(resource, pattern, sortBy string) ([]Message, error) {
+func (g *Grepper) GrepResourceWithoutNamespace(resource string, matcher match.Matcher, sortBy string) ([]Message, error) {
 	namespace, err := g.getDefaultNamespace()
 	if err != nil {
 		return nil, fmt.Errorf("error getting default namespace: %v", err)
 	}
-	return g.Grep(namespace, resource, pattern, sortBy)
+	return g.Grep(namespace, resource, matcher, sortBy)
 }
 
 // GrepNamespace searches for a pattern in logs across all pods in a specific namespace.
-func (g *Grepper) GrepNamespace(namespace, pattern, sortBy string) ([]Message, error) {
-	return g.Grep(namespace, "", pattern, sortBy)
+func (g *Grepper) GrepNamespace(namespace string, matcher match.Matcher, sortBy string) ([]Message, error) {
+	return g.Grep(namespace, "", matcher, sortBy)
 }
 
 // Grep searches for a pattern in logs of a specific resource in a specific namespace.
-func (g *Grepper) Grep(namespace, resource, pattern, sortBy string) ([]Message, error) {
-	if g.clientset == nil {
-		return nil, fmt.Errorf("Kubernetes clientset not available")
+func (g *Grepper) Grep(namespace, resource string, matcher match.Matcher, sortBy string) ([]Message, error) {
+	var messages []Message
+	err := g.GrepStream(namespace, resource, matcher, func(message Message) {
+		messages = append(messages, message)
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	pods, err := g.getPods(namespace, resource)
+	return g.sortMessages(messages, sortBy), nil
+}
+
+// GrepAllNamespaces searches for a pattern in the logs of a specific resource,
+// or of all pods if resource is empty, across all namespaces.
+func (g *Grepper) GrepAllNamespaces(resource string, matcher match.Matcher, sortBy string) ([]Message, error) {
+	return g.Grep(metav1.NamespaceAll, resource, matcher, sortBy)
+}
+
+// GrepAllNamespacesStream is like GrepStream across all namespaces. Pods are
+// listed cluster-wide rather than namespace by namespace.
+func (g *Grepper) GrepAllNamespacesStream(resource string, matcher match.Matcher, emit func(Message)) error {
+	return g.GrepStream(metav1.NamespaceAll, resource, matcher, emit)
+}
+
+// GrepWithoutNamespaceStream is like GrepStream in the default namespace.
+func (g *Grepper) GrepWithoutNamespaceStream(resource string, matcher match.Matcher, emit func(Message)) error {
+	namespace, err := g.getDefaultNamespace()
 	if err != nil {
-		return nil, fmt.Errorf("error getting pods: %v", err)
+		return fmt.Errorf("error getting default namespace: %v", err)
 	}
+	return g.GrepStream(namespace, resource, matcher, emit)
+}
 
-	var messages []Message
-	for _, pod := range pods {
-		podMessages := g.searchPodLogs(pod, pattern)
-		messages = append(messages, podMessages...)
+// GrepStream searches for a pattern in logs of a specific resource in a
+// specific namespace, or of all pods if resource is empty, and calls emit with
+// each message as it's found. Messages are emitted unsorted, in pod and
+// container order, and emit is never called concurrently.
+func (g *Grepper) GrepStream(namespace, resource string, matcher match.Matcher, emit func(Message)) error {
+	if g.clientset == nil {
+		return fmt.Errorf("Kubernetes clientset not available")
 	}
 
-	return g.sortMessages(messages, sortBy), nil
+	err := g.listPods(namespace, resource, func(pods []corev1.Pod) error {
+		g.searchPodsLogs(pods, matcher, emit)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error getting pods: %v", err)
+	}
+
+	return nil
+}
+
+// NeedsSort reports whether sorting by sortBy requires all messages to be
+// collected first, so that they can't be printed as they're found.
+func NeedsSort(sortBy string) bool {
+	switch strings.ToUpper(sortBy) {
+	case "TIMESTAMP", "MESSAGE", "POD_AND_CONTAINER":
+		return true
+	}
+	return false
 }
 
 // getDefaultNamespace gets the default namespace from kubeconfig.
@@ -136,135 +191,418 @@ func (g *Grepper) getDefaultNamespace() (string, error) {
 	return namespace, nil
 }
 
-// getPods gets pods in a namespace, optionally filtered by resource name.
-func (g *Grepper) getPods(namespace, resource string) ([]corev1.Pod, error) {
-	pods, err := g.clientset.CoreV1().Pods(namespace).List(context.Background(), metav1.ListOptions{})
+// listPods lists the pods of a resource, or every pod if resource is empty, in
+// a namespace, or in all namespaces if it's empty. Pods are listed in chunks
+// of Options.ChunkSize, and visit is called with each chunk.
+func (g *Grepper) listPods(namespace, resource string, visit func([]corev1.Pod) error) error {
+	ctx := context.Background()
+	filter, err := g.newPodFilter(ctx, namespace, resource, false)
 	if err != nil {
-		return nil, err
+		return err
+	}
+
+	for _, source := range filter.sources {
+		err := pager.Pages(ctx, source.listOptions(g.options.ChunkSize),
+			func(ctx context.Context, options metav1.ListOptions) ([]corev1.Pod, string, error) {
+				pods, err := g.clientset.CoreV1().Pods(source.namespace).List(ctx, options)
+				if err != nil {
+					return nil, "", err
+				}
+				return pods.Items, pods.Continue, nil
+			},
+			func(pod corev1.Pod) string { return pod.Namespace + "/" + pod.Name },
+			func(pods []corev1.Pod) error {
+				var selected []corev1.Pod
+				for _, pod := range pods {
+					if filter.selects(ctx, source, &pod) {
+						selected = append(selected, pod)
+					}
+				}
+				return visit(selected)
+			})
+		if err != nil {
+			return err
+		}
 	}
 
-	if resource == "" {
-		return pods.Items, nil
+	return nil
+}
+
+// containerLog identifies the log of a container instance.
+type containerLog struct {
+	pod           corev1.Pod
+	container     string
+	containerType ContainerType
+	// previous selects the log of the previous instance of the container.
+	previous bool
+}
+
+func (l containerLog) String() string {
+	s := fmt.Sprintf("pod %s/%s container %s", l.pod.Namespace, l.pod.Name, l.container)
+	if l.previous {
+		s += " (previous)"
+	}
+	return s
+}
+
+// searchPodsLogs fetches and searches the logs of every container of the pods
+// concurrently. Messages are emitted in pod and container order, and logs
+// that can't be fetched or are only partially searched are reported through
+// Options.OnError.
+func (g *Grepper) searchPodsLogs(pods []corev1.Pod, matcher match.Matcher, emit func(Message)) {
+	var targets []containerLog
+	for _, pod := range pods {
+		targets = append(targets, g.getContainerLogs(pod)...)
 	}
 
-	// Filter pods by resource name
-	var filteredPods []corev1.Pod
-	for _, pod := range pods.Items {
-		if strings.Contains(pod.Name, resource) {
-			filteredPods = append(filteredPods, pod)
+	results := make([][]Message, len(targets))
+	tasks := make([]func() error, len(targets))
+	for i, target := range targets {
+		tasks[i] = func() error {
+			logs, err := g.logReader.GetPodLogs(target.pod.Namespace, target.pod.Name, g.podLogOptions(target.container, target.previous))
+			if err != nil {
+				return err
+			}
+			defer logs.Close()
+
+			// Messages found before a stream is aborted are kept.
+			results[i], err = g.searchLogs(logs, matcher, target.pod.Name, target.container)
+			for j := range results[i] {
+				results[i][j].Namespace = target.pod.Namespace
+				results[i][j].ContainerType = target.containerType
+				results[i][j].Previous = target.previous
+			}
+			return err
 		}
 	}
 
-	return filteredPods, nil
+	worker.RunOrdered(g.options.Concurrency, tasks, func(i int, err error) {
+		if err != nil && g.options.OnError != nil {
+			g.options.OnError(fmt.Errorf("%s: %v", targets[i], err))
+		}
+		for _, message := range results[i] {
+			emit(message)
+		}
+		results[i] = nil
+	})
 }
 
-// searchPodLogs now uses the LogReader interface instead of a direct clientset call.
-func (g *Grepper) searchPodLogs(pod corev1.Pod, pattern string) []Message {
-	var messages []Message
+// getContainerLogs lists the container logs of a pod to search, according to
+// Options.Containers, Options.ContainerTypes and Options.Instances. Previous
+// instances only exist for containers that restarted, and come before the
+// current ones.
+func (g *Grepper) getContainerLogs(pod corev1.Pod) []containerLog {
+	var logs []containerLog
+	for _, container := range g.getContainers(pod) {
+		if !g.selectsContainer(container) {
+			continue
+		}
+		if g.options.Instances != InstancesCurrent && restartCount(pod, container.name) > 0 {
+			logs = append(logs, containerLog{pod: pod, container: container.name, containerType: container.containerType, previous: true})
+		}
+		if g.options.Instances != InstancesPrevious {
+			logs = append(logs, containerLog{pod: pod, container: container.name, containerType: container.containerType})
+		}
+	}
+	return logs
+}
 
-	containers := g.getContainerNames(pod)
+// selectsContainer reports whether a container matches the container name
+// patterns and types of the options. Without them every container is selected.
+func (g *Grepper) selectsContainer(container podContainer) bool {
+	if len(g.options.ContainerTypes) > 0 && !slices.Contains(g.options.ContainerTypes, container.containerType) {
+		return false
+	}
 
-	for _, container := range containers {
-		logs, err := g.logReader.GetPodLogs(pod.Namespace, pod.Name, container)
-		if err != nil {
-			continue
+	if len(g.options.Containers) == 0 {
+		return true
+	}
+	for _, pattern := range g.options.Containers {
+		if matched, _ := path.Match(pattern, container.name); matched {
+			return true
 		}
+	}
+	return false
+}
 
-		containerMessages := g.searchLogs(logs, pattern, pod.Name, container)
-		messages = append(messages, containerMessages...)
+// restartCount returns the number of times a container of a pod restarted.
+func restartCount(pod corev1.Pod, container string) int32 {
+	if status := containerStatus(pod, container); status != nil {
+		return status.RestartCount
 	}
+	return 0
+}
+
+// podLogOptions selects the logs of a container to search. Lines are requested
+// with the time the container runtime received them, which is used to sort
+// messages and to apply Options.Until.
+func (g *Grepper) podLogOptions(container string, previous bool) *corev1.PodLogOptions {
+	options := &corev1.PodLogOptions{
+		Container:  container,
+		Previous:   previous,
+		Timestamps: true,
+	}
+
+	if g.options.Since > 0 {
+		// The API server only accepts whole seconds.
+		seconds := int64(math.Ceil(g.options.Since.Seconds()))
+		options.SinceSeconds = &seconds
+	}
+	if !g.options.SinceTime.IsZero() {
+		sinceTime := metav1.NewTime(g.options.SinceTime)
+		options.SinceTime = &sinceTime
+	}
+	if g.options.TailLines > 0 {
+		tailLines := g.options.TailLines
+		options.TailLines = &tailLines
+	}
+
+	return options
+}
 
-	return messages
+// podContainer is a container of a pod and its type.
+type podContainer struct {
+	name          string
+	containerType ContainerType
 }
 
-// getContainerNames gets container names from a pod.
-func (g *Grepper) getContainerNames(pod corev1.Pod) []string {
-	var containers []string
+// getContainers gets the containers of a pod in the order they start: init
+// containers, including sidecars, then regular and ephemeral containers.
+func (g *Grepper) getContainers(pod corev1.Pod) []podContainer {
+	var containers []podContainer
+
+	for _, container := range pod.Spec.InitContainers {
+		containerType := ContainerTypeInit
+		if container.RestartPolicy != nil && *container.RestartPolicy == corev1.ContainerRestartPolicyAlways {
+			// Init containers that keep running are native sidecars.
+			containerType = ContainerTypeSidecar
+		}
+		containers = append(containers, podContainer{name: container.Name, containerType: containerType})
+	}
 
 	// Get containers from pod spec
+	regular := len(pod.Spec.Containers)
 	for _, container := range pod.Spec.Containers {
-		containers = append(containers, container.Name)
+		containers = append(containers, podContainer{name: container.Name, containerType: ContainerTypeRegular})
 	}
 
 	// If no containers found in spec, try to get from status
-	if len(containers) == 0 {
+	if regular == 0 {
 		for _, containerStatus := range pod.Status.ContainerStatuses {
-			containers = append(containers, containerStatus.Name)
+			containers = append(containers, podContainer{name: containerStatus.Name, containerType: ContainerTypeRegular})
 		}
 	}
 
+	for _, container := range pod.Spec.EphemeralContainers {
+		containers = append(containers, podContainer{name: container.Name, containerType: ContainerTypeEphemeral})
+	}
+
 	return containers
 }
 
-// searchLogs searches for a pattern in log content.
-func (g *Grepper) searchLogs(logs, pattern, podName, containerName string) []Message {
+// searchLogs searches for a pattern in a log stream, line by line.
+// A nil matcher matches every line.
+//
+// Lines longer than Options.MaxLineLength are truncated. The messages found
+// are returned along with an error if lines were truncated or the stream was
+// aborted.
+func (g *Grepper) searchLogs(logs io.Reader, matcher match.Matcher, podName, containerName string) ([]Message, error) {
 	var messages []Message
+	searcher := g.newLineSearcher(matcher, podName, containerName, func(message Message) {
+		messages = append(messages, message)
+	})
 
-	// If the pattern is empty, we match every line.
-	if pattern == "" {
-		scanner := bufio.NewScanner(strings.NewReader(logs))
-		lineNumber := 1
-		for scanner.Scan() {
-			messages = append(messages, Message{
-				PodName:       podName,
-				ContainerName: containerName,
-				Message:       scanner.Text(),
-				LineNumber:    lineNumber,
-			})
-			lineNumber++
+	maxLineLength := g.maxLineLength()
+	reader := bufio.NewReader(logs)
+	truncatedLines := 0
+	lineNumber := 1
+	for {
+		line, truncated, err := readLine(reader, maxLineLength)
+		if err == io.EOF {
+			break
 		}
-		return messages
+		if err != nil {
+			searcher.flush()
+			return messages, fmt.Errorf("log stream aborted after line %d: %v", lineNumber-1, err)
+		}
+		if truncated {
+			truncatedLines++
+		}
+		timestamp, line := parseTimestamp(line)
+		if !g.options.Until.IsZero() && timestamp.After(g.options.Until) {
+			// Logs are chronological, so the rest of the stream is past the window.
+			break
+		}
+
+		searcher.search(lineNumber, timestamp, line)
+		lineNumber++
 	}
+	searcher.flush()
 
-	// Otherwise, search for the pattern in each line.
-	scanner := bufio.NewScanner(strings.NewReader(logs))
-	lineNumber := 1
-	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.Contains(strings.ToLower(line), strings.ToLower(pattern)) {
-			messages = append(messages, Message{
-				PodName:       podName,
-				ContainerName: containerName,
-				Message:       line,
-				LineNumber:    lineNumber,
-			})
+	if truncatedLines > 0 {
+		return messages, fmt.Errorf("%d line(s) longer than %d bytes were truncated", truncatedLines, maxLineLength)
+	}
+
+	return messages, nil
+}
+
+// maxLineLength returns the length above which log lines are truncated.
+func (g *Grepper) maxLineLength() int {
+	if g.options.MaxLineLength <= 0 {
+		return DefaultMaxLineLength
+	}
+	return g.options.MaxLineLength
+}
+
+// lineSearcher matches the lines of a log one at a time, attaching context
+// lines to the messages found. Messages are emitted once their context is
+// complete, so that logs can be searched as they're written.
+type lineSearcher struct {
+	matcher       match.Matcher
+	podName       string
+	containerName string
+	beforeContext int
+	afterContext  int
+	structured    bool
+	where         *predicate.Predicate
+	levels        []Level
+	emit          func(Message)
+
+	// before holds the lines seen since the last match, up to beforeContext.
+	before []ContextLine
+	// pending is the last match, still waiting for pendingAfter context lines.
+	pending      *Message
+	pendingAfter int
+}
+
+func (g *Grepper) newLineSearcher(matcher match.Matcher, podName, containerName string, emit func(Message)) *lineSearcher {
+	return &lineSearcher{
+		matcher:       matcher,
+		podName:       podName,
+		containerName: containerName,
+		beforeContext: g.options.BeforeContext,
+		afterContext:  g.options.AfterContext,
+		structured:    g.options.Structured || g.options.Where != nil,
+		where:         g.options.Where,
+		levels:        g.options.Levels,
+		emit:          emit,
+	}
+}
+
+// search matches a line. A nil matcher matches every line satisfying the
+// where predicate, if any.
+func (s *lineSearcher) search(lineNumber int, timestamp time.Time, line string) {
+	var fields map[string]interface{}
+	if s.structured {
+		fields = parseFields(line)
+	}
+
+	var spans []match.Span
+	matched := true
+	if s.where != nil {
+		matched = false
+		if fields != nil {
+			// Lines lacking the fields the predicate refers to don't match.
+			matched, _ = s.where.EvaluateFields(fields)
+		}
+	}
+	if matched && s.matcher != nil {
+		spans, matched = s.matcher.Match(line)
+	}
+
+	// Levels are only detected for the lines matching the pattern and predicate.
+	var level Level
+	if matched {
+		levelFields := fields
+		if !s.structured {
+			levelFields = parseFields(line)
+		}
+		level = detectLevel(line, levelFields)
+		if len(s.levels) > 0 && !slices.Contains(s.levels, level) {
+			matched = false
 		}
-		lineNumber++
 	}
 
-	return messages
+	switch {
+	case matched:
+		s.flush()
+		s.pending = &Message{
+			PodName:       s.podName,
+			ContainerName: s.containerName,
+			Message:       line,
+			LineNumber:    lineNumber,
+			Timestamp:     timestamp,
+			Matches:       spans,
+			Patterns:      match.Patterns(spans),
+			Fields:        fields,
+			Level:         level,
+			Before:        s.before,
+		}
+		s.before = nil
+		s.pendingAfter = s.afterContext
+	case s.pendingAfter > 0:
+		s.pending.After = append(s.pending.After, ContextLine{LineNumber: lineNumber, Timestamp: timestamp, Message: line})
+		s.pendingAfter--
+	case s.beforeContext > 0:
+		if len(s.before) == s.beforeContext {
+			s.before = s.before[1:]
+		}
+		s.before = append(s.before, ContextLine{LineNumber: lineNumber, Timestamp: timestamp, Message: line})
+	}
+
+	if s.pendingAfter == 0 {
+		s.flush()
+	}
 }
 
-// sortMessages sorts messages based on the sortBy parameter.
-func (g *Grepper) sortMessages(messages []Message, sortBy string) []Message {
-	switch strings.ToUpper(sortBy) {
-	case "MESSAGE":
-		// Sort by message content
-		for i := 0; i < len(messages)-1; i++ {
-			for j := i + 1; j < len(messages); j++ {
-				if messages[i].Message > messages[j].Message {
-					messages[i], messages[j] = messages[j], messages[i]
-				}
+// flush emits the last match, even if its after context is incomplete.
+func (s *lineSearcher) flush() {
+	if s.pending != nil {
+		s.emit(*s.pending)
+		s.pending = nil
+		s.pendingAfter = 0
+	}
+}
+
+// parseTimestamp splits the timestamp prefixed to a log line by the API server
+// from the message. Lines without a timestamp are returned unchanged with a
+// zero time.
+func parseTimestamp(line string) (time.Time, string) {
+	prefix, message, found := strings.Cut(line, " ")
+	if !found {
+		prefix, message = line, ""
+	}
+
+	timestamp, err := time.Parse(time.RFC3339Nano, prefix)
+	if err != nil {
+		return time.Time{}, line
+	}
+	return timestamp, message
+}
+
+// readLine reads a line without its line ending. Only the first maxLength
+// bytes of longer lines are kept, and the rest is discarded. io.EOF is only
+// returned when there are no more lines.
+func readLine(reader *bufio.Reader, maxLength int) (string, bool, error) {
+	var line []byte
+	truncated := false
+	for {
+		chunk, isPrefix, err := reader.ReadLine()
+		if err != nil {
+			if err == io.EOF && (line != nil || truncated) {
+				return string(line), truncated, nil
 			}
+			return "", false, err
 		}
-	case "POD_AND_CONTAINER":
-		// Default sort: by pod name, then container name, then line number
-		for i := 0; i < len(messages)-1; i++ {
-			for j := i + 1; j < len(messages); j++ {
-				if messages[i].PodName > messages[j].PodName {
-					messages[i], messages[j] = messages[j], messages[i]
-				} else if messages[i].PodName == messages[j].PodName {
-					if messages[i].ContainerName > messages[j].ContainerName {
-						messages[i], messages[j] = messages[j], messages[i]
-					} else if messages[i].ContainerName == messages[j].ContainerName {
-						if messages[i].LineNumber > messages[j].LineNumber {
-							messages[i], messages[j] = messages[j], messages[i]
-						}
-					}
-				}
-			}
+
+		if room := maxLength - len(line); len(chunk) > room {
+			chunk = chunk[:room]
+			truncated = true
 		}
-	}
+		line = append(line, chunk...)
 
-	return messages
+		if !isPrefix {
+			return string(line), truncated, nil
+		}
+	}
 }
@@ -2,13 +2,20 @@ package log
 
 import (
 	"fmt"
+	"io"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/hbelmiro/kgrep/internal/match"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
 )
 
 // --- Test Setup with FakeLogReader ---
@@ -32,13 +39,29 @@ func (f *FakeLogReader) addLog(namespace, podName, containerName, content string
 	f.logs[key] = content
 }
 
+// addPreviousLog adds log content for the previous instance of a container.
+func (f *FakeLogReader) addPreviousLog(namespace, podName, containerName, content string) {
+	f.addLog(namespace, podName, containerName+"/previous", content)
+}
+
 // GetPodLogs retrieves the stored log content for a pod.
-func (f *FakeLogReader) GetPodLogs(namespace, podName, containerName string) (string, error) {
-	key := fmt.Sprintf("%s/%s/%s", namespace, podName, containerName)
+func (f *FakeLogReader) GetPodLogs(namespace, podName string, options *corev1.PodLogOptions) (io.ReadCloser, error) {
+	key := fmt.Sprintf("%s/%s/%s", namespace, podName, options.Container)
+	if options.Previous {
+		key += "/previous"
+	}
 	if content, found := f.logs[key]; found {
-		return content, nil
+		return io.NopCloser(strings.NewReader(content)), nil
 	}
-	return "", fmt.Errorf("logs not found for pod %s", podName)
+	return nil, fmt.Errorf("logs not found for pod %s", podName)
+}
+
+// newMatcher compiles a pattern with the default matching options.
+func newMatcher(t *testing.T, pattern string) match.Matcher {
+	t.Helper()
+	matcher, err := match.New(pattern, match.Options{})
+	require.NoError(t, err)
+	return matcher
 }
 
 // --- Tests ---
@@ -64,15 +87,16 @@ func TestLogGrepper_InteractionWithAPIServer(t *testing.T) {
 	grepper := &Grepper{
 		clientset: fakeClientset,
 		logReader: fakeLogReader,
+		options:   Options{PodNameContains: true},
 	}
 
-	messages, err := grepper.Grep("test", "pod", "initialized", "POD_AND_CONTAINER")
+	messages, err := grepper.Grep("test", "pod", newMatcher(t, "initialized"), "POD_AND_CONTAINER")
 	require.NoError(t, err)
 
 	expectedMessages := []Message{
-		{PodName: "pod1", ContainerName: "container1", Message: "xpto initialized", LineNumber: 2},
-		{PodName: "pod2", ContainerName: "container2", Message: "foo initialized", LineNumber: 2},
-		{PodName: "pod2", ContainerName: "container2", Message: "bar initialized", LineNumber: 5},
+		{Namespace: "test", PodName: "pod1", ContainerName: "container1", ContainerType: ContainerTypeRegular, Message: "xpto initialized", LineNumber: 2, Matches: []match.Span{{Start: 5, End: 16, Pattern: "initialized"}}, Patterns: []string{"initialized"}},
+		{Namespace: "test", PodName: "pod2", ContainerName: "container2", ContainerType: ContainerTypeRegular, Message: "foo initialized", LineNumber: 2, Matches: []match.Span{{Start: 4, End: 15, Pattern: "initialized"}}, Patterns: []string{"initialized"}},
+		{Namespace: "test", PodName: "pod2", ContainerName: "container2", ContainerType: ContainerTypeRegular, Message: "bar initialized", LineNumber: 5, Matches: []match.Span{{Start: 4, End: 15, Pattern: "initialized"}}, Patterns: []string{"initialized"}},
 	}
 	assert.ElementsMatch(t, expectedMessages, messages)
 }
@@ -91,9 +115,10 @@ func TestLogGrepper_ResourceFiltering(t *testing.T) {
 	grepper := &Grepper{
 		clientset: fakeClientset,
 		logReader: fakeLogReader,
+		options:   Options{PodNameContains: true},
 	}
 
-	messages, err := grepper.Grep("default", "app", "pattern", "POD_AND_CONTAINER")
+	messages, err := grepper.Grep("default", "app", newMatcher(t, "pattern"), "POD_AND_CONTAINER")
 	require.NoError(t, err)
 
 	assert.Len(t, messages, 1)
@@ -131,6 +156,49 @@ func TestLogGrepper_SortMessages(t *testing.T) {
 	})
 }
 
+func TestLogGrepper_GrepAllNamespaces(t *testing.T) {
+	pods := []runtime.Object{
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "staging"},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "production"},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "worker", Namespace: "production"},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+		},
+	}
+
+	fakeLogReader := newFakeLogReader()
+	fakeLogReader.addLog("staging", "api", "app", "staging error")
+	fakeLogReader.addLog("production", "api", "app", "production error")
+	fakeLogReader.addLog("production", "worker", "app", "worker error")
+
+	fakeClientset := fake.NewClientset(pods...)
+	grepper := &Grepper{clientset: fakeClientset, logReader: fakeLogReader}
+
+	messages, err := grepper.GrepAllNamespaces("api", newMatcher(t, "error"), "POD_AND_CONTAINER")
+	require.NoError(t, err)
+
+	require.Len(t, messages, 2)
+	assert.Equal(t, "production", messages[0].Namespace)
+	assert.Equal(t, "production error", messages[0].Message)
+	assert.Equal(t, "staging", messages[1].Namespace)
+	assert.Equal(t, "staging error", messages[1].Message)
+
+	// The pod is looked up, then its pods are listed once, cluster-wide.
+	var lists []string
+	for _, action := range fakeClientset.Actions() {
+		if action.GetVerb() == "list" {
+			lists = append(lists, action.GetNamespace())
+		}
+	}
+	assert.Equal(t, []string{"", ""}, lists)
+}
+
 func TestLogGrepper_GetPodLogs_Error(t *testing.T) {
 	fakeLogReader := newFakeLogReader()
 	pod1 := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "test"}}
@@ -141,18 +209,412 @@ func TestLogGrepper_GetPodLogs_Error(t *testing.T) {
 		logReader: fakeLogReader,
 	}
 
-	messages, err := grepper.Grep("test", "pod1", "any-pattern", "")
+	messages, err := grepper.Grep("test", "pod1", newMatcher(t, "any-pattern"), "")
 	require.NoError(t, err)
 	assert.Empty(t, messages)
 }
 
+func TestLogGrepper_Grep_ConcurrentAndOrdered(t *testing.T) {
+	fakeLogReader := newFakeLogReader()
+	var objects []runtime.Object
+	for i := 1; i <= 5; i++ {
+		name := fmt.Sprintf("pod%d", i)
+		objects = append(objects, &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test"},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}, {Name: "sidecar"}}},
+		})
+		fakeLogReader.addLog("test", name, "app", "error in app of "+name)
+		if i != 3 {
+			fakeLogReader.addLog("test", name, "sidecar", "error in sidecar of "+name)
+		}
+	}
+
+	var errs []error
+	grepper := &Grepper{
+		clientset: fake.NewClientset(objects...),
+		logReader: fakeLogReader,
+		options:   Options{Concurrency: 4, OnError: func(err error) { errs = append(errs, err) }},
+	}
+
+	messages, err := grepper.Grep("test", "", newMatcher(t, "error"), "")
+	require.NoError(t, err)
+
+	var sources []string
+	for _, message := range messages {
+		sources = append(sources, message.PodName+"/"+message.ContainerName)
+	}
+	assert.Equal(t, []string{
+		"pod1/app", "pod1/sidecar",
+		"pod2/app", "pod2/sidecar",
+		"pod3/app",
+		"pod4/app", "pod4/sidecar",
+		"pod5/app", "pod5/sidecar",
+	}, sources)
+
+	require.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Error(), "pod test/pod3 container sidecar")
+}
+
+func TestLogGrepper_Grep_Chunks(t *testing.T) {
+	fakeLogReader := newFakeLogReader()
+	var pods []corev1.Pod
+	for i := 1; i <= 5; i++ {
+		name := fmt.Sprintf("web-%d", i)
+		pods = append(pods, corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test"},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+		})
+		fakeLogReader.addLog("test", name, "app", "error in "+name)
+	}
+
+	// The fake clientset ignores limits, so pages are served by a reactor
+	// continuing from the index of their first pod.
+	var limits []int64
+	fakeClientset := fake.NewClientset()
+	fakeClientset.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		options := action.(k8stesting.ListActionImpl).ListOptions
+		limits = append(limits, options.Limit)
+
+		start, _ := strconv.Atoi(options.Continue)
+		end := min(start+int(options.Limit), len(pods))
+		list := &corev1.PodList{Items: pods[start:end]}
+		if end < len(pods) {
+			list.Continue = strconv.Itoa(end)
+		}
+		return true, list, nil
+	})
+
+	grepper := &Grepper{
+		clientset: fakeClientset,
+		logReader: fakeLogReader,
+		options:   Options{ChunkSize: 2, PodNameContains: true},
+	}
+
+	messages, err := grepper.Grep("test", "web", newMatcher(t, "error"), "")
+	require.NoError(t, err)
+	assert.Len(t, messages, 5)
+	assert.Equal(t, "web-1", messages[0].PodName)
+	assert.Equal(t, "web-5", messages[4].PodName)
+	assert.Equal(t, []int64{2, 2, 2}, limits)
+}
+
 func TestLogGrepper_SearchLogs_EmptyPattern(t *testing.T) {
 	grepper := &Grepper{}
 	logContent := "line 1\nline 2\nline 3"
-	messages := grepper.searchLogs(logContent, "", "pod1", "c1")
+	messages, err := grepper.searchLogs(strings.NewReader(logContent), newMatcher(t, ""), "pod1", "c1")
+	require.NoError(t, err)
 
 	assert.Len(t, messages, 3)
 	assert.Equal(t, "line 1", messages[0].Message)
 	assert.Equal(t, "line 2", messages[1].Message)
 	assert.Equal(t, "line 3", messages[2].Message)
 }
+
+func TestLogGrepper_SearchLogs_Regexp(t *testing.T) {
+	grepper := &Grepper{}
+	logContent := "level=info ready\nlevel=error failed\nlevel=fatal crashed"
+	matcher, err := match.New(`level=(error|fatal)`, match.Options{Regexp: true})
+	require.NoError(t, err)
+
+	messages, err := grepper.searchLogs(strings.NewReader(logContent), matcher, "pod1", "c1")
+	require.NoError(t, err)
+
+	require.Len(t, messages, 2)
+	assert.Equal(t, 2, messages[0].LineNumber)
+	assert.Equal(t, []match.Span{{Start: 0, End: 11, Pattern: "level=(error|fatal)"}}, messages[0].Matches)
+	assert.Equal(t, 3, messages[1].LineNumber)
+}
+
+func TestLogGrepper_SearchLogs_Context(t *testing.T) {
+	grepper := &Grepper{}
+	grepper.SetOptions(Options{BeforeContext: 2, AfterContext: 1})
+	logContent := "connecting\nretrying\nwaiting\nerror: refused\nerror: timeout\nshutting down\nbye"
+
+	messages, err := grepper.searchLogs(strings.NewReader(logContent), newMatcher(t, "error"), "pod1", "c1")
+	require.NoError(t, err)
+
+	require.Len(t, messages, 2)
+	assert.Equal(t, []ContextLine{{LineNumber: 2, Message: "retrying"}, {LineNumber: 3, Message: "waiting"}}, messages[0].Before)
+	assert.Empty(t, messages[0].After, "the next line is a match and is reported on its own")
+	assert.Empty(t, messages[1].Before)
+	assert.Equal(t, []ContextLine{{LineNumber: 6, Message: "shutting down"}}, messages[1].After)
+}
+
+func TestLogGrepper_SearchLogs_LongLines(t *testing.T) {
+	grepper := &Grepper{}
+	grepper.SetOptions(Options{MaxLineLength: 10})
+	logContent := "short\n" + strings.Repeat("x", 100) + " error\nend x"
+
+	messages, err := grepper.searchLogs(strings.NewReader(logContent), newMatcher(t, "x"), "pod1", "c1")
+
+	require.Len(t, messages, 2, "lines after a long line are still searched")
+	assert.Equal(t, strings.Repeat("x", 10), messages[0].Message)
+	assert.Equal(t, 2, messages[0].LineNumber)
+	assert.Equal(t, "end x", messages[1].Message)
+	require.Error(t, err)
+	assert.Equal(t, "1 line(s) longer than 10 bytes were truncated", err.Error())
+}
+
+func TestLogGrepper_SearchLogs_LongerThanScannerBuffer(t *testing.T) {
+	grepper := &Grepper{}
+	logContent := strings.Repeat("a", 100*1024) + " error\nnext error"
+
+	messages, err := grepper.searchLogs(strings.NewReader(logContent), newMatcher(t, "error"), "pod1", "c1")
+
+	require.NoError(t, err)
+	require.Len(t, messages, 2)
+	assert.Len(t, messages[0].Message, 100*1024+6)
+	assert.Equal(t, "next error", messages[1].Message)
+}
+
+// abortingReader returns its content and then fails, like a log stream whose
+// connection is reset.
+type abortingReader struct {
+	content io.Reader
+}
+
+func (r *abortingReader) Read(p []byte) (int, error) {
+	n, err := r.content.Read(p)
+	if err == io.EOF {
+		return n, fmt.Errorf("connection reset by peer")
+	}
+	return n, err
+}
+
+func TestLogGrepper_SearchLogs_AbortedStream(t *testing.T) {
+	grepper := &Grepper{}
+	logs := &abortingReader{content: strings.NewReader("error one\nok\nerror two\n")}
+
+	messages, err := grepper.searchLogs(logs, newMatcher(t, "error"), "pod1", "c1")
+
+	assert.Len(t, messages, 2, "messages found before the stream is aborted are kept")
+	require.Error(t, err)
+	assert.Equal(t, "log stream aborted after line 3: connection reset by peer", err.Error())
+}
+
+func TestLogGrepper_Grep_ReportsAbortedStreams(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "test"},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+	}
+
+	var errs []error
+	grepper := &Grepper{
+		clientset: fake.NewClientset(pod),
+		logReader: readerFunc(func(string, string, *corev1.PodLogOptions) (io.ReadCloser, error) {
+			return io.NopCloser(&abortingReader{content: strings.NewReader("error one\n")}), nil
+		}),
+		options: Options{OnError: func(err error) { errs = append(errs, err) }},
+	}
+
+	messages, err := grepper.Grep("test", "", newMatcher(t, "error"), "")
+	require.NoError(t, err)
+	assert.Len(t, messages, 1)
+	require.Len(t, errs, 1)
+	assert.Equal(t, "pod test/pod1 container app: log stream aborted after line 1: connection reset by peer", errs[0].Error())
+}
+
+type readerFunc func(namespace, podName string, options *corev1.PodLogOptions) (io.ReadCloser, error)
+
+func (f readerFunc) GetPodLogs(namespace, podName string, options *corev1.PodLogOptions) (io.ReadCloser, error) {
+	return f(namespace, podName, options)
+}
+
+func TestLogGrepper_GrepStream(t *testing.T) {
+	pod1 := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "test"},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "b"}, {Name: "a"}}},
+	}
+	fakeLogReader := newFakeLogReader()
+	fakeLogReader.addLog("test", "pod1", "b", "error 1\nerror 2")
+	fakeLogReader.addLog("test", "pod1", "a", "error 3")
+
+	grepper := &Grepper{clientset: fake.NewClientset(pod1), logReader: fakeLogReader}
+
+	var streamed []string
+	err := grepper.GrepStream("test", "", newMatcher(t, "error"), func(message Message) {
+		streamed = append(streamed, message.ContainerName+": "+message.Message)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b: error 1", "b: error 2", "a: error 3"}, streamed, "messages are streamed in container order")
+
+	sorted, err := grepper.Grep("test", "", newMatcher(t, "error"), "pod_and_container")
+	require.NoError(t, err)
+	assert.Equal(t, "a", sorted[0].ContainerName)
+}
+
+func TestNeedsSort(t *testing.T) {
+	assert.True(t, NeedsSort("message"))
+	assert.True(t, NeedsSort("POD_AND_CONTAINER"))
+	assert.True(t, NeedsSort("timestamp"))
+	assert.False(t, NeedsSort(""))
+	assert.False(t, NeedsSort("none"))
+}
+
+func TestLogGrepper_SearchLogs_Timestamps(t *testing.T) {
+	grepper := &Grepper{}
+	grepper.SetOptions(Options{BeforeContext: 1})
+	logContent := "2025-03-01T10:00:00.123456789Z starting\n2025-03-01T10:00:01Z error: refused\nno timestamp error"
+
+	messages, err := grepper.searchLogs(strings.NewReader(logContent), newMatcher(t, "error"), "pod1", "c1")
+	require.NoError(t, err)
+
+	require.Len(t, messages, 2)
+	assert.Equal(t, "error: refused", messages[0].Message)
+	assert.Equal(t, time.Date(2025, 3, 1, 10, 0, 1, 0, time.UTC), messages[0].Timestamp)
+	assert.Equal(t, []match.Span{{Start: 0, End: 5, Pattern: "error"}}, messages[0].Matches, "spans are relative to the message")
+	assert.Equal(t, []ContextLine{{LineNumber: 1, Timestamp: time.Date(2025, 3, 1, 10, 0, 0, 123456789, time.UTC), Message: "starting"}}, messages[0].Before)
+	assert.Equal(t, "no timestamp error", messages[1].Message)
+	assert.True(t, messages[1].Timestamp.IsZero())
+}
+
+func TestLogGrepper_PodLogOptions(t *testing.T) {
+	sinceTime := time.Date(2025, 3, 1, 14, 2, 0, 0, time.UTC)
+	grepper := &Grepper{}
+
+	grepper.SetOptions(Options{Since: 90500 * time.Millisecond, TailLines: 100})
+	options := grepper.podLogOptions("app", false)
+	assert.Equal(t, "app", options.Container)
+	assert.True(t, options.Timestamps)
+	require.NotNil(t, options.SinceSeconds)
+	assert.Equal(t, int64(91), *options.SinceSeconds, "partial seconds are rounded up")
+	require.NotNil(t, options.TailLines)
+	assert.Equal(t, int64(100), *options.TailLines)
+	assert.Nil(t, options.SinceTime)
+
+	grepper.SetOptions(Options{SinceTime: sinceTime})
+	options = grepper.podLogOptions("app", false)
+	require.NotNil(t, options.SinceTime)
+	assert.True(t, options.SinceTime.Time.Equal(sinceTime))
+	assert.Nil(t, options.SinceSeconds)
+	assert.Nil(t, options.TailLines)
+}
+
+func TestLogGrepper_SearchLogs_Until(t *testing.T) {
+	grepper := &Grepper{}
+	grepper.SetOptions(Options{Until: time.Date(2025, 3, 1, 14, 10, 0, 0, time.UTC)})
+	logContent := "2025-03-01T14:02:00Z error: first\n" +
+		"no timestamp error\n" +
+		"2025-03-01T14:10:00Z error: at the bound\n" +
+		"2025-03-01T14:10:00.5Z error: too late\n" +
+		"2025-03-01T14:11:00Z error: too late"
+
+	messages, err := grepper.searchLogs(strings.NewReader(logContent), newMatcher(t, "error"), "pod1", "c1")
+	require.NoError(t, err)
+
+	var found []string
+	for _, message := range messages {
+		found = append(found, message.Message)
+	}
+	assert.Equal(t, []string{"error: first", "no timestamp error", "error: at the bound"}, found)
+}
+
+func TestLogGrepper_Grep_PreviousInstances(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "test"},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}, {Name: "sidecar"}}},
+		Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{
+			{Name: "app", RestartCount: 3},
+			{Name: "sidecar"},
+		}},
+	}
+	fakeLogReader := newFakeLogReader()
+	fakeLogReader.addLog("test", "pod1", "app", "error: current app")
+	fakeLogReader.addPreviousLog("test", "pod1", "app", "error: crashed app")
+	fakeLogReader.addLog("test", "pod1", "sidecar", "error: current sidecar")
+
+	testCases := []struct {
+		instances Instances
+		expected  []string
+	}{
+		{instances: InstancesCurrent, expected: []string{"app: error: current app", "sidecar: error: current sidecar"}},
+		{instances: InstancesPrevious, expected: []string{"app (previous): error: crashed app"}},
+		{instances: InstancesAll, expected: []string{"app (previous): error: crashed app", "app: error: current app", "sidecar: error: current sidecar"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(string(tc.instances), func(t *testing.T) {
+			var errs []error
+			grepper := &Grepper{
+				clientset: fake.NewClientset(pod),
+				logReader: fakeLogReader,
+				options:   Options{Instances: tc.instances, OnError: func(err error) { errs = append(errs, err) }},
+			}
+
+			messages, err := grepper.Grep("test", "", newMatcher(t, "error"), "")
+			require.NoError(t, err)
+			assert.Empty(t, errs, "containers without restarts have no previous logs to fetch")
+
+			var found []string
+			for _, message := range messages {
+				source := message.ContainerName
+				if message.Previous {
+					source += " (previous)"
+				}
+				found = append(found, source+": "+message.Message)
+			}
+			assert.Equal(t, tc.expected, found)
+		})
+	}
+}
+
+func TestLogGrepper_Grep_ContainerTypes(t *testing.T) {
+	always := corev1.ContainerRestartPolicyAlways
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "test"},
+		Spec: corev1.PodSpec{
+			InitContainers: []corev1.Container{{Name: "migrate"}, {Name: "istio-proxy", RestartPolicy: &always}},
+			Containers:     []corev1.Container{{Name: "app"}},
+			EphemeralContainers: []corev1.EphemeralContainer{
+				{EphemeralContainerCommon: corev1.EphemeralContainerCommon{Name: "debugger"}},
+			},
+		},
+		Status: corev1.PodStatus{InitContainerStatuses: []corev1.ContainerStatus{{Name: "migrate", RestartCount: 1}}},
+	}
+	fakeLogReader := newFakeLogReader()
+	for _, container := range []string{"migrate", "istio-proxy", "app", "debugger"} {
+		fakeLogReader.addLog("test", "pod1", container, "error in "+container)
+	}
+	fakeLogReader.addPreviousLog("test", "pod1", "migrate", "error in the first migration")
+
+	testCases := []struct {
+		name     string
+		options  Options
+		expected []string
+	}{
+		{
+			name:     "all containers in start order",
+			expected: []string{"migrate (init)", "istio-proxy (sidecar)", "app (regular)", "debugger (ephemeral)"},
+		},
+		{
+			name:     "name globs",
+			options:  Options{Containers: []string{"istio-*", "app"}},
+			expected: []string{"istio-proxy (sidecar)", "app (regular)"},
+		},
+		{
+			name:     "types",
+			options:  Options{ContainerTypes: []ContainerType{ContainerTypeInit, ContainerTypeEphemeral}},
+			expected: []string{"migrate (init)", "debugger (ephemeral)"},
+		},
+		{
+			name:     "previous init containers",
+			options:  Options{Instances: InstancesPrevious},
+			expected: []string{"migrate (init)"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			grepper := &Grepper{clientset: fake.NewClientset(pod), logReader: fakeLogReader, options: tc.options}
+
+			messages, err := grepper.Grep("test", "", newMatcher(t, "error"), "")
+			require.NoError(t, err)
+
+			var found []string
+			for _, message := range messages {
+				found = append(found, fmt.Sprintf("%s (%s)", message.ContainerName, message.ContainerType))
+			}
+			assert.Equal(t, tc.expected, found)
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package log
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Level is the severity of a log line. Levels are ordered from the least to
+// the most severe.
+type Level int
+
+const (
+	// LevelUnknown is the level of lines whose severity can't be detected.
+	LevelUnknown Level = iota
+	LevelTrace
+	LevelDebug
+	LevelInfo
+	LevelWarn
+	LevelError
+	LevelFatal
+)
+
+// Levels lists the known levels, from the least to the most severe.
+var Levels = []Level{LevelTrace, LevelDebug, LevelInfo, LevelWarn, LevelError, LevelFatal}
+
+var levelNames = map[Level]string{
+	LevelUnknown: "unknown",
+	LevelTrace:   "trace",
+	LevelDebug:   "debug",
+	LevelInfo:    "info",
+	LevelWarn:    "warn",
+	LevelError:   "error",
+	LevelFatal:   "fatal",
+}
+
+// levelAliases maps the level names used by common logging libraries, in
+// lower case, to levels.
+var levelAliases = map[string]Level{
+	"trace":         LevelTrace,
+	"finest":        LevelTrace,
+	"finer":         LevelTrace,
+	"debug":         LevelDebug,
+	"dbug":          LevelDebug,
+	"fine":          LevelDebug,
+	"info":          LevelInfo,
+	"information":   LevelInfo,
+	"informational": LevelInfo,
+	"notice":        LevelInfo,
+	"warn":          LevelWarn,
+	"warning":       LevelWarn,
+	"error":         LevelError,
+	"err":           LevelError,
+	"eror":          LevelError,
+	"severe":        LevelError,
+	"dpanic":        LevelError,
+	"fatal":         LevelFatal,
+	"critical":      LevelFatal,
+	"crit":          LevelFatal,
+	"panic":         LevelFatal,
+	"alert":         LevelFatal,
+	"emerg":         LevelFatal,
+	"emergency":     LevelFatal,
+}
+
+func (l Level) String() string {
+	if name, found := levelNames[l]; found {
+		return name
+	}
+	return levelNames[LevelUnknown]
+}
+
+// ParseLevel parses the name of a level, ignoring case. Besides the names of
+// Levels, it accepts the aliases used by common logging libraries, like
+// warning, critical or panic.
+func ParseLevel(name string) (Level, bool) {
+	level, found := levelAliases[strings.ToLower(name)]
+	return level, found
+}
+
+// levelFields are the fields holding the level of structured lines, as used
+// by logrus, zap, slog, Bunyan, Pino, Elastic Common Schema and Python JSON
+// loggers.
+var levelFields = []string{"level", "lvl", "severity", "log.level", "levelname", "loglevel"}
+
+// klogHeader matches the header of klog lines, like E0612 10:31:02.123456,
+// whose first letter is the level.
+var klogHeader = regexp.MustCompile(`^([IWEF])\d{4} \d{2}:\d{2}:\d{2}`)
+
+var klogLevels = map[byte]Level{'I': LevelInfo, 'W': LevelWarn, 'E': LevelError, 'F': LevelFatal}
+
+// maxLevelPrefixWords is the number of words at the start of plain text lines
+// searched for a level, which is enough to skip the timestamps, thread and
+// logger names formats like Log4j or Python logging print before it.
+const maxLevelPrefixWords = 6
+
+// detectLevel detects the level of a log line from the level field of
+// structured lines, the header of klog lines, or a level name among the first
+// words of plain text lines, like ERROR or [warn].
+func detectLevel(line string, fields map[string]interface{}) Level {
+	if fields != nil {
+		return fieldsLevel(fields)
+	}
+
+	if header := klogHeader.FindStringSubmatch(line); header != nil {
+		return klogLevels[header[1][0]]
+	}
+
+	return prefixLevel(line)
+}
+
+// fieldsLevel returns the level held by the fields of a structured line.
+func fieldsLevel(fields map[string]interface{}) Level {
+	for _, field := range levelFields {
+		value, found := fields[field]
+		if !found {
+			// ECS nests the level under log.
+			key, rest, nested := strings.Cut(field, ".")
+			if object, ok := fields[key].(map[string]interface{}); ok && nested {
+				value, found = object[rest]
+			}
+		}
+		if !found {
+			continue
+		}
+
+		switch v := value.(type) {
+		case string:
+			if level, ok := ParseLevel(strings.TrimSpace(v)); ok {
+				return level
+			}
+		case int64:
+			return numericLevel(float64(v))
+		case float64:
+			return numericLevel(v)
+		}
+	}
+	return LevelUnknown
+}
+
+// numericLevel converts the numeric levels of Bunyan and Pino, from 10 for
+// trace to 60 for fatal.
+func numericLevel(value float64) Level {
+	switch {
+	case value >= 60:
+		return LevelFatal
+	case value >= 50:
+		return LevelError
+	case value >= 40:
+		return LevelWarn
+	case value >= 30:
+		return LevelInfo
+	case value >= 20:
+		return LevelDebug
+	case value >= 10:
+		return LevelTrace
+	}
+	return LevelUnknown
+}
+
+// prefixLevel searches the first words of a plain text line for a level name.
+// Names must be upper case, like ERROR or WARN:, unless they're in brackets,
+// like [error], so that words of the message aren't taken for levels.
+func prefixLevel(line string) Level {
+	rest := line
+	for i := 0; i < maxLevelPrefixWords; i++ {
+		rest = strings.TrimLeft(rest, " \t")
+		if rest == "" {
+			break
+		}
+
+		word := rest
+		if end := strings.IndexAny(rest, " \t"); end >= 0 {
+			word, rest = rest[:end], rest[end:]
+		} else {
+			rest = ""
+		}
+
+		bracketed := strings.HasPrefix(word, "[") || strings.HasPrefix(word, "<")
+		// Python logging prints levels like ERROR:root:message.
+		word, _, _ = strings.Cut(strings.TrimLeft(word, "[<("), ":")
+		word = strings.TrimRight(word, "]>),|")
+		if !bracketed && word != strings.ToUpper(word) {
+			continue
+		}
+		if level, ok := ParseLevel(word); ok {
+			return level
+		}
+	}
+	return LevelUnknown
+}
@@ -0,0 +1,93 @@
+package log
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectLevel(t *testing.T) {
+	testCases := []struct {
+		name     string
+		line     string
+		expected Level
+	}{
+		{name: "klog error", line: `E0612 10:31:02.123456       1 controller.go:114] "Failed to sync" err="timeout"`, expected: LevelError},
+		{name: "klog warning", line: "W0612 10:31:02.123456       1 reflector.go:535] watch closed", expected: LevelWarn},
+		{name: "klog info", line: "I0612 10:31:02.123456       1 main.go:42] starting", expected: LevelInfo},
+		{name: "klog fatal", line: "F0612 10:31:02.123456       1 main.go:50] cannot start", expected: LevelFatal},
+		{name: "logrus JSON", line: `{"level":"warning","msg":"disk almost full","time":"2024-05-01T10:00:00Z"}`, expected: LevelWarn},
+		{name: "zap JSON", line: `{"level":"error","ts":1714557600.1,"caller":"server/handler.go:88","msg":"request failed"}`, expected: LevelError},
+		{name: "zap dpanic", line: `{"level":"dpanic","msg":"invalid state"}`, expected: LevelError},
+		{name: "slog JSON", line: `{"time":"2024-05-01T10:00:00Z","level":"WARN","msg":"slow query"}`, expected: LevelWarn},
+		{name: "Bunyan numeric level", line: `{"name":"api","level":50,"msg":"request failed"}`, expected: LevelError},
+		{name: "ECS nested level", line: `{"log":{"level":"debug"},"message":"cache hit"}`, expected: LevelDebug},
+		{name: "severity field", line: `{"severity":"CRITICAL","message":"out of memory"}`, expected: LevelFatal},
+		{name: "logfmt", line: `time=2024-05-01T10:00:00Z level=error msg="connection refused"`, expected: LevelError},
+		{name: "structured line without level", line: `{"msg":"ERROR in message"}`, expected: LevelUnknown},
+		{name: "Log4j", line: "2024-05-01 10:00:00,123 ERROR [main] com.example.App - connection refused", expected: LevelError},
+		{name: "Logback", line: "10:00:00.123 [http-nio-8080-exec-1] WARN  c.e.Controller - slow request", expected: LevelWarn},
+		{name: "Spring Boot", line: "2024-05-01T10:00:00.123Z  INFO 1 --- [main] c.e.Application : Started", expected: LevelInfo},
+		{name: "Python logging", line: "ERROR:root:connection refused", expected: LevelError},
+		{name: "Python logging with format", line: "2024-05-01 10:00:00,123 - app.db - WARNING - retrying", expected: LevelWarn},
+		{name: "bracketed lower case", line: "2024/05/01 10:00:00 [error] 7#7: *1 connect() failed", expected: LevelError},
+		{name: "lower case word in message", line: "request failed with error: timeout", expected: LevelUnknown},
+		{name: "level past the first words", line: "one two three four five six ERROR", expected: LevelUnknown},
+		{name: "plain text", line: "listening on :8080", expected: LevelUnknown},
+		{name: "empty", line: "", expected: LevelUnknown},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, detectLevel(tc.line, parseFields(tc.line)))
+		})
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for _, level := range Levels {
+		parsed, ok := ParseLevel(strings.ToUpper(level.String()))
+		require.True(t, ok, level.String())
+		assert.Equal(t, level, parsed)
+	}
+
+	level, ok := ParseLevel("Warning")
+	assert.True(t, ok)
+	assert.Equal(t, LevelWarn, level)
+
+	_, ok = ParseLevel("verbose")
+	assert.False(t, ok)
+	assert.Equal(t, "unknown", LevelUnknown.String())
+}
+
+func TestLogGrepper_SearchLogs_Levels(t *testing.T) {
+	grepper := &Grepper{}
+	logContent := strings.Join([]string{
+		"I0612 10:31:02.123456       1 main.go:42] starting",
+		"W0612 10:31:03.123456       1 main.go:43] retrying",
+		`{"level":"error","msg":"request failed"}`,
+		"\tat com.example.App.main(App.java:12)",
+		"2024-05-01 10:00:00,123 FATAL [main] shutting down",
+	}, "\n")
+
+	messages, err := grepper.searchLogs(strings.NewReader(logContent), newMatcher(t, ""), "pod1", "c1")
+	require.NoError(t, err)
+	require.Len(t, messages, 5)
+	assert.Equal(t, LevelInfo, messages[0].Level)
+	assert.Equal(t, LevelWarn, messages[1].Level)
+	assert.Equal(t, LevelError, messages[2].Level)
+	assert.Nil(t, messages[2].Fields, "fields are only exposed for structured searches")
+	assert.Equal(t, LevelUnknown, messages[3].Level)
+	assert.Equal(t, LevelFatal, messages[4].Level)
+
+	grepper.SetOptions(Options{Levels: []Level{LevelWarn, LevelError, LevelFatal}, AfterContext: 1})
+	messages, err = grepper.searchLogs(strings.NewReader(logContent), nil, "pod1", "c1")
+	require.NoError(t, err)
+	require.Len(t, messages, 3)
+	assert.Equal(t, 2, messages[0].LineNumber)
+	assert.Equal(t, 3, messages[1].LineNumber)
+	assert.Equal(t, []ContextLine{{LineNumber: 4, Message: "\tat com.example.App.main(App.java:12)"}}, messages[1].After)
+	assert.Equal(t, 5, messages[2].LineNumber)
+}
@@ -1,9 +1,43 @@
 package log
 
+import (
+	"time"
+
+	"github.com/hbelmiro/kgrep/internal/match"
+)
+
 // Message represents a log message from a Kubernetes pod.
 type Message struct {
+	Namespace     string
 	PodName       string
 	ContainerName string
-	LineNumber    int
-	Message       string
+	ContainerType ContainerType
+	// Previous is set for messages logged by the previous instance of the
+	// container, before it restarted.
+	Previous   bool
+	LineNumber int
+	// Timestamp is when the container runtime received the line, or the zero
+	// time if the log has no timestamps.
+	Timestamp time.Time
+	Message   string
+	Matches   []match.Span
+	// Patterns lists the patterns that matched, which is useful when
+	// searching for several patterns at once.
+	Patterns []string
+	// Fields holds the fields of structured lines when Options.Structured or
+	// Options.Where is set. It's nil for other lines.
+	Fields map[string]interface{}
+	// Level is the severity detected for the line, from its level field or
+	// a level name at its start, or LevelUnknown.
+	Level Level
+	// Before and After hold the context lines surrounding the match.
+	Before []ContextLine
+	After  []ContextLine
+}
+
+// ContextLine is a log line surrounding a matching message.
+type ContextLine struct {
+	LineNumber int
+	Timestamp  time.Time
+	Message    string
 }
@@ -0,0 +1,107 @@
+package log
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/hbelmiro/kgrep/internal/predicate"
+)
+
+// DefaultMaxLineLength is the length in bytes above which log lines are truncated by default.
+const DefaultMaxLineLength = 1024 * 1024
+
+// Instances selects which instances of containers are searched.
+type Instances string
+
+const (
+	// InstancesCurrent searches the running instance of containers.
+	InstancesCurrent Instances = ""
+	// InstancesPrevious searches the previous instance of containers that
+	// restarted, e.g. because they crashed, and skips other containers.
+	InstancesPrevious Instances = "previous"
+	// InstancesAll searches the previous instance of containers that
+	// restarted, followed by the running instance.
+	InstancesAll Instances = "all"
+)
+
+// ContainerType is the kind of a container within a pod.
+type ContainerType string
+
+const (
+	// ContainerTypeRegular is a container of the pod's main workload.
+	ContainerTypeRegular ContainerType = "regular"
+	// ContainerTypeInit is an init container, run to completion before the
+	// regular containers start.
+	ContainerTypeInit ContainerType = "init"
+	// ContainerTypeSidecar is an init container that keeps running alongside
+	// the regular containers.
+	ContainerTypeSidecar ContainerType = "sidecar"
+	// ContainerTypeEphemeral is a container added to a running pod, e.g. with
+	// kubectl debug.
+	ContainerTypeEphemeral ContainerType = "ephemeral"
+)
+
+// ContainerTypes lists the valid container types.
+var ContainerTypes = []ContainerType{ContainerTypeRegular, ContainerTypeInit, ContainerTypeSidecar, ContainerTypeEphemeral}
+
+// Options configures optional Grepper behavior.
+type Options struct {
+	// BeforeContext and AfterContext are the number of lines to include
+	// before and after each matching line.
+	BeforeContext int
+	AfterContext  int
+	// Concurrency is the maximum number of container logs fetched at once.
+	// Values below 1 fetch logs sequentially.
+	Concurrency int
+	// ChunkSize is the maximum number of pods listed per request. The logs of
+	// each chunk are searched as it arrives. Zero lists all pods at once.
+	ChunkSize int64
+	// MaxLineLength is the length in bytes above which log lines are
+	// truncated. Zero means DefaultMaxLineLength.
+	MaxLineLength int
+	// Since and SinceTime only search the lines logged after a relative or
+	// absolute time. Only one of them may be set. They're applied by the API
+	// server, like TailLines.
+	Since     time.Duration
+	SinceTime time.Time
+	// Until stops searching each log at the first line logged after it.
+	// It's applied client-side using the timestamps of the lines.
+	Until time.Time
+	// TailLines only searches the given number of lines at the end of each
+	// log. Zero searches whole logs.
+	TailLines int64
+	// Instances defaults to InstancesCurrent.
+	Instances Instances
+	// Containers only searches the containers whose names match one of the
+	// glob patterns, like app or istio-*.
+	Containers []string
+	// ContainerTypes only searches containers of the given types.
+	ContainerTypes []ContainerType
+	// LabelSelector and FieldSelector only search the pods they select.
+	// Phase and NodeName only search the pods in a phase, or scheduled on a
+	// node. They're all passed to the API server when pods are listed, along
+	// with the selectors of the searched resource.
+	LabelSelector string
+	FieldSelector string
+	Phase         corev1.PodPhase
+	NodeName      string
+	// Structured parses the fields of structured lines, logged as JSON objects
+	// or in logfmt, into Message.Fields.
+	Structured bool
+	// Where only matches the structured lines whose fields satisfy the
+	// predicate, created with predicate.NewFields. Lines that aren't
+	// structured, or lack the fields it refers to, don't match. It implies
+	// Structured.
+	Where *predicate.Predicate
+	// Levels only matches the lines detected at one of the given levels.
+	// Lines whose level can't be detected don't match.
+	Levels []Level
+	// PodNameContains selects the pods whose names contain the resource given
+	// to the search methods, instead of resolving it as a workload, a service
+	// or a pod name.
+	PodNameContains bool
+	// OnError is called with the errors that don't stop a search, such as
+	// logs that can't be fetched. Errors are ignored if it's nil.
+	OnError func(error)
+}
@@ -0,0 +1,105 @@
+package log
+
+import (
+	"container/heap"
+	"sort"
+	"strings"
+)
+
+// sortMessages sorts messages based on the sortBy parameter.
+func (g *Grepper) sortMessages(messages []Message, sortBy string) []Message {
+	switch strings.ToUpper(sortBy) {
+	case "TIMESTAMP":
+		return mergeByTimestamp(messages)
+	case "MESSAGE":
+		// Sort by message content
+		sort.SliceStable(messages, func(i, j int) bool {
+			return messages[i].Message < messages[j].Message
+		})
+	case "POD_AND_CONTAINER":
+		// Sort by namespace, pod name, then container name, then line number
+		sort.SliceStable(messages, func(i, j int) bool {
+			a, b := messages[i], messages[j]
+			if a.Namespace != b.Namespace {
+				return a.Namespace < b.Namespace
+			}
+			if a.PodName != b.PodName {
+				return a.PodName < b.PodName
+			}
+			if a.ContainerName != b.ContainerName {
+				return a.ContainerName < b.ContainerName
+			}
+			if a.Previous != b.Previous {
+				// Previous instances logged first.
+				return a.Previous
+			}
+			return a.LineNumber < b.LineNumber
+		})
+	}
+
+	return messages
+}
+
+// mergeByTimestamp merges the messages of all containers into chronological
+// order. The messages of each container are already chronological, so they're
+// merged with a heap of one run per container instead of being sorted.
+// Messages with equal timestamps keep their original order.
+func mergeByTimestamp(messages []Message) []Message {
+	var runs runHeap
+	start := 0
+	for i := 1; i <= len(messages); i++ {
+		if i == len(messages) || !sameSource(messages[i], messages[start]) {
+			runs = append(runs, run{messages: messages[start:i], order: len(runs)})
+			start = i
+		}
+	}
+	heap.Init(&runs)
+
+	merged := make([]Message, 0, len(messages))
+	for runs.Len() > 0 {
+		next := &runs[0]
+		merged = append(merged, next.messages[0])
+		next.messages = next.messages[1:]
+		if len(next.messages) == 0 {
+			heap.Pop(&runs)
+		} else {
+			heap.Fix(&runs, 0)
+		}
+	}
+	return merged
+}
+
+func sameSource(a, b Message) bool {
+	return a.Namespace == b.Namespace && a.PodName == b.PodName && a.ContainerName == b.ContainerName && a.Previous == b.Previous
+}
+
+// run is the remaining messages of a container, in chronological order.
+type run struct {
+	messages []Message
+	// order breaks ties between runs, keeping their original order.
+	order int
+}
+
+// runHeap is a min-heap of runs ordered by the timestamp of their next message.
+type runHeap []run
+
+func (h runHeap) Len() int { return len(h) }
+
+func (h runHeap) Less(i, j int) bool {
+	a, b := h[i].messages[0].Timestamp, h[j].messages[0].Timestamp
+	if !a.Equal(b) {
+		return a.Before(b)
+	}
+	return h[i].order < h[j].order
+}
+
+func (h runHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *runHeap) Push(x any) { *h = append(*h, x.(run)) }
+
+func (h *runHeap) Pop() any {
+	old := *h
+	last := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return last
+}
@@ -0,0 +1,69 @@
+package log
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func at(seconds int) time.Time {
+	return time.Date(2025, 3, 1, 10, 0, seconds, 0, time.UTC)
+}
+
+func TestMergeByTimestamp(t *testing.T) {
+	messages := []Message{
+		{PodName: "pod-a", ContainerName: "app", Message: "a1", Timestamp: at(1)},
+		{PodName: "pod-a", ContainerName: "app", Message: "a4", Timestamp: at(4)},
+		{PodName: "pod-a", ContainerName: "sidecar", Message: "s2", Timestamp: at(2)},
+		{PodName: "pod-a", ContainerName: "sidecar", Message: "s4", Timestamp: at(4)},
+		{PodName: "pod-b", ContainerName: "app", Message: "b0", Timestamp: at(0)},
+		{PodName: "pod-b", ContainerName: "app", Message: "b3", Timestamp: at(3)},
+	}
+
+	var order []string
+	for _, message := range mergeByTimestamp(messages) {
+		order = append(order, message.Message)
+	}
+	assert.Equal(t, []string{"b0", "a1", "s2", "b3", "a4", "s4"}, order, "ties keep the order of the containers")
+}
+
+func TestMergeByTimestamp_Namespaces(t *testing.T) {
+	messages := []Message{
+		{Namespace: "staging", PodName: "api", ContainerName: "app", Message: "s1", Timestamp: at(1)},
+		{Namespace: "staging", PodName: "api", ContainerName: "app", Message: "s3", Timestamp: at(3)},
+		{Namespace: "production", PodName: "api", ContainerName: "app", Message: "p0", Timestamp: at(0)},
+		{Namespace: "production", PodName: "api", ContainerName: "app", Message: "p2", Timestamp: at(2)},
+	}
+
+	var order []string
+	for _, message := range mergeByTimestamp(messages) {
+		order = append(order, message.Message)
+	}
+	assert.Equal(t, []string{"p0", "s1", "p2", "s3"}, order, "pods with the same name in different namespaces are different sources")
+}
+
+func TestMergeByTimestamp_Empty(t *testing.T) {
+	assert.Empty(t, mergeByTimestamp(nil))
+}
+
+func TestMergeByTimestamp_ManyContainers(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+
+	var messages []Message
+	for container := 0; container < 50; container++ {
+		seconds := 0
+		for line := 0; line < 20; line++ {
+			seconds += random.Intn(10)
+			messages = append(messages, Message{ContainerName: fmt.Sprint(container), Timestamp: at(seconds)})
+		}
+	}
+
+	merged := mergeByTimestamp(messages)
+	assert.Len(t, merged, len(messages))
+	for i := 1; i < len(merged); i++ {
+		assert.False(t, merged[i].Timestamp.Before(merged[i-1].Timestamp), "message %d is out of order", i)
+	}
+}
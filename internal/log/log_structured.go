@@ -0,0 +1,123 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// parseFields parses the fields of a structured log line, logged as a JSON
+// object or in logfmt. It returns nil for other lines.
+func parseFields(line string) map[string]interface{} {
+	if fields := parseJSONFields(line); fields != nil {
+		return fields
+	}
+	return parseLogfmtFields(line)
+}
+
+// parseJSONFields parses a line holding a JSON object. Integers are kept as
+// int64, so that large ones like IDs aren't rounded.
+func parseJSONFields(line string) map[string]interface{} {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "{") {
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader([]byte(line)))
+	decoder.UseNumber()
+	var fields map[string]interface{}
+	if err := decoder.Decode(&fields); err != nil || decoder.More() {
+		return nil
+	}
+
+	return normalizeJSON(fields).(map[string]interface{})
+}
+
+// normalizeJSON converts the numbers of a decoded JSON value to int64 or float64.
+func normalizeJSON(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = normalizeJSON(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normalizeJSON(item)
+		}
+	}
+	return value
+}
+
+// parseLogfmtFields parses a line of key=value pairs separated by spaces, like
+// level=error msg="request failed" latency_ms=730. Values may be quoted. Lines
+// with anything other than pairs aren't considered logfmt, so that plain text
+// mentioning a key=value isn't parsed. Unquoted numbers and booleans are
+// converted, so that they can be compared as such.
+func parseLogfmtFields(line string) map[string]interface{} {
+	fields := map[string]interface{}{}
+	for i := 0; i < len(line); {
+		if line[i] == ' ' || line[i] == '\t' {
+			i++
+			continue
+		}
+
+		start := i
+		for i < len(line) && line[i] != '=' && line[i] != ' ' && line[i] != '"' {
+			i++
+		}
+		key := line[start:i]
+		if key == "" || i == len(line) || line[i] != '=' {
+			return nil
+		}
+		i++
+
+		if i < len(line) && line[i] == '"' {
+			quoted, err := strconv.QuotedPrefix(line[i:])
+			if err != nil {
+				return nil
+			}
+			value, err := strconv.Unquote(quoted)
+			if err != nil {
+				return nil
+			}
+			fields[key] = value
+			i += len(quoted)
+			continue
+		}
+
+		start = i
+		for i < len(line) && line[i] != ' ' && line[i] != '\t' {
+			i++
+		}
+		fields[key] = logfmtValue(line[start:i])
+	}
+
+	if len(fields) == 0 {
+		return nil
+	}
+	return fields
+}
+
+func logfmtValue(value string) interface{} {
+	if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return i
+	}
+	// Words like NaN or Inf are parsed as floats too.
+	if f, err := strconv.ParseFloat(value, 64); err == nil && strings.ContainsAny(value, "0123456789") {
+		return f
+	}
+	switch value {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	return value
+}
@@ -0,0 +1,93 @@
+package log
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hbelmiro/kgrep/internal/predicate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFields(t *testing.T) {
+	testCases := []struct {
+		name     string
+		line     string
+		expected map[string]interface{}
+	}{
+		{
+			name:     "JSON",
+			line:     `{"level":"error","msg":"request failed","latency_ms":730,"ratio":0.5,"ok":false,"http":{"status":503}}`,
+			expected: map[string]interface{}{"level": "error", "msg": "request failed", "latency_ms": int64(730), "ratio": 0.5, "ok": false, "http": map[string]interface{}{"status": int64(503)}},
+		},
+		{
+			name:     "JSON with large integers",
+			line:     `  {"id": 9007199254740993}  `,
+			expected: map[string]interface{}{"id": int64(9007199254740993)},
+		},
+		{
+			name:     "logfmt",
+			line:     `level=error msg="request \"GET /\" failed" latency_ms=730 ratio=0.5 ok=true path=/api empty=`,
+			expected: map[string]interface{}{"level": "error", "msg": `request "GET /" failed`, "latency_ms": int64(730), "ratio": 0.5, "ok": true, "path": "/api", "empty": ""},
+		},
+		{
+			name:     "logfmt keeps words parsed as floats",
+			line:     `value=NaN quoted="730"`,
+			expected: map[string]interface{}{"value": "NaN", "quoted": "730"},
+		},
+		{name: "plain text", line: "request failed with status=503"},
+		{name: "JSON array", line: `["error"]`},
+		{name: "JSON followed by text", line: `{"level":"error"} trailing`},
+		{name: "unterminated quote", line: `level=error msg="request failed`},
+		{name: "empty", line: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, parseFields(tc.line))
+		})
+	}
+}
+
+func TestLogGrepper_SearchLogs_Where(t *testing.T) {
+	where, err := predicate.NewFields(`level == "error" && latency_ms > 500`)
+	require.NoError(t, err)
+
+	grepper := &Grepper{}
+	grepper.SetOptions(Options{Where: where})
+	logContent := strings.Join([]string{
+		`{"level":"error","msg":"slow","latency_ms":730}`,
+		`{"level":"error","msg":"fast","latency_ms":20}`,
+		`{"level":"info","msg":"slow","latency_ms":900}`,
+		`level=error msg="slow too" latency_ms=501.5`,
+		`level=error msg="no latency"`,
+		`error: latency_ms 900`,
+	}, "\n")
+
+	messages, err := grepper.searchLogs(strings.NewReader(logContent), nil, "pod1", "c1")
+	require.NoError(t, err)
+
+	require.Len(t, messages, 2)
+	assert.Equal(t, 1, messages[0].LineNumber)
+	assert.Equal(t, "slow", messages[0].Fields["msg"])
+	assert.Equal(t, 4, messages[1].LineNumber)
+	assert.Equal(t, "slow too", messages[1].Fields["msg"])
+
+	// The pattern must match too.
+	messages, err = grepper.searchLogs(strings.NewReader(logContent), newMatcher(t, "too"), "pod1", "c1")
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.Equal(t, 4, messages[0].LineNumber)
+}
+
+func TestLogGrepper_SearchLogs_Structured(t *testing.T) {
+	grepper := &Grepper{}
+	grepper.SetOptions(Options{Structured: true})
+
+	messages, err := grepper.searchLogs(strings.NewReader("{\"msg\":\"error\"}\nerror"), newMatcher(t, "error"), "pod1", "c1")
+	require.NoError(t, err)
+
+	require.Len(t, messages, 2)
+	assert.Equal(t, map[string]interface{}{"msg": "error"}, messages[0].Fields)
+	assert.Nil(t, messages[1].Fields)
+}
@@ -0,0 +1,473 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+// podSource is a set of pods whose logs are searched: the pods of a workload
+// or a service, a single pod, or every pod of a namespace.
+type podSource struct {
+	namespace string
+	// selector and fieldSelector select the pods. They're pushed to the API
+	// server, and checked again client-side.
+	selector      labels.Selector
+	fieldSelector fields.Selector
+	// controller is the workload controlling the pods, through intermediate
+	// controllers of the kinds in chain, e.g. ReplicaSet for deployments. It's
+	// nil if the pods aren't selected by owner, e.g. for services.
+	controller *metav1.OwnerReference
+	chain      []string
+	// nameContains selects pods by a substring of their name.
+	nameContains string
+}
+
+func (s podSource) listOptions(limit int64) metav1.ListOptions {
+	return metav1.ListOptions{
+		LabelSelector: s.selector.String(),
+		FieldSelector: s.fieldSelector.String(),
+		Limit:         limit,
+	}
+}
+
+// workloadKind resolves the pods of resources of a kind.
+type workloadKind struct {
+	name    string
+	aliases []string
+	resolve resolveFunc
+}
+
+// resolveFunc returns the pods of the resources of a kind with a name, in a
+// namespace or in all namespaces.
+type resolveFunc func(ctx context.Context, clientset kubernetes.Interface, namespace, name string) ([]podSource, error)
+
+// workloadKinds lists the kinds of resources whose pods can be searched.
+var workloadKinds = []workloadKind{
+	{name: "pod", aliases: []string{"pods", "po"}, resolve: resolvePods},
+	{name: "deployment", aliases: []string{"deployments", "deploy"}, resolve: resolveWorkloads("Deployment", listDeployments,
+		func(deployment *appsv1.Deployment) *metav1.LabelSelector { return deployment.Spec.Selector }, "ReplicaSet")},
+	{name: "statefulset", aliases: []string{"statefulsets", "sts"}, resolve: resolveWorkloads("StatefulSet", listStatefulSets,
+		func(statefulSet *appsv1.StatefulSet) *metav1.LabelSelector { return statefulSet.Spec.Selector })},
+	{name: "daemonset", aliases: []string{"daemonsets", "ds"}, resolve: resolveWorkloads("DaemonSet", listDaemonSets,
+		func(daemonSet *appsv1.DaemonSet) *metav1.LabelSelector { return daemonSet.Spec.Selector })},
+	{name: "replicaset", aliases: []string{"replicasets", "rs"}, resolve: resolveWorkloads("ReplicaSet", listReplicaSets,
+		func(replicaSet *appsv1.ReplicaSet) *metav1.LabelSelector { return replicaSet.Spec.Selector })},
+	{name: "job", aliases: []string{"jobs"}, resolve: resolveWorkloads("Job", listJobs,
+		func(job *batchv1.Job) *metav1.LabelSelector { return job.Spec.Selector })},
+	// Each job of a cron job has its own selector, so pods are only selected by owner.
+	{name: "cronjob", aliases: []string{"cronjobs", "cj"}, resolve: resolveWorkloads("CronJob", listCronJobs,
+		func(*batchv1.CronJob) *metav1.LabelSelector { return &metav1.LabelSelector{} }, "Job")},
+	{name: "service", aliases: []string{"services", "svc"}, resolve: resolveServices},
+}
+
+// findWorkloadKind finds a kind by name or alias, ignoring case.
+func findWorkloadKind(name string) (workloadKind, bool) {
+	name = strings.ToLower(name)
+	for _, kind := range workloadKinds {
+		if kind.name == name || slices.Contains(kind.aliases, name) {
+			return kind, true
+		}
+	}
+	return workloadKind{}, false
+}
+
+// podSources resolves the resource given to the search methods into the pods
+// to search. Resources have the form kind/name, like deployment/api, or are
+// pod names. An empty resource selects every pod of the namespace. When
+// following, a named pod doesn't need to exist yet.
+func (g *Grepper) podSources(ctx context.Context, namespace, resource string, follow bool) ([]podSource, error) {
+	selector, fieldSelector, err := g.podSelectors()
+	if err != nil {
+		return nil, err
+	}
+
+	sources, err := g.resolveResource(ctx, namespace, resource, follow)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the pods selected by both the resource and the options are searched.
+	requirements, _ := selector.Requirements()
+	for i := range sources {
+		sources[i].selector = sources[i].selector.Add(requirements...)
+		sources[i].fieldSelector, err = andFieldSelectors(sources[i].fieldSelector, fieldSelector)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return sources, nil
+}
+
+// podSelectors returns the selectors of the pods to search from the options.
+func (g *Grepper) podSelectors() (labels.Selector, fields.Selector, error) {
+	selector, err := labels.Parse(g.options.LabelSelector)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid label selector: %v", err)
+	}
+
+	fieldSelector, err := fields.ParseSelector(g.options.FieldSelector)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid field selector: %v", err)
+	}
+
+	var terms []fields.Selector
+	if g.options.Phase != "" {
+		terms = append(terms, fields.OneTermEqualSelector("status.phase", string(g.options.Phase)))
+	}
+	if g.options.NodeName != "" {
+		terms = append(terms, fields.OneTermEqualSelector("spec.nodeName", g.options.NodeName))
+	}
+	fieldSelector, err = andFieldSelectors(fieldSelector, terms...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return selector, fieldSelector, nil
+}
+
+// andFieldSelectors combines field selectors. Unlike fields.AndSelectors, the
+// result can be passed to the API server even if some of them are empty.
+func andFieldSelectors(selector fields.Selector, others ...fields.Selector) (fields.Selector, error) {
+	var terms []string
+	for _, s := range append([]fields.Selector{selector}, others...) {
+		if !s.Empty() {
+			terms = append(terms, s.String())
+		}
+	}
+
+	combined, err := fields.ParseSelector(strings.Join(terms, ","))
+	if err != nil {
+		return nil, fmt.Errorf("invalid field selector: %v", err)
+	}
+	return combined, nil
+}
+
+// resolveResource resolves a resource into the pods to search, before the
+// selectors of the options are applied.
+func (g *Grepper) resolveResource(ctx context.Context, namespace, resource string, follow bool) ([]podSource, error) {
+	all := podSource{namespace: namespace, selector: labels.Everything(), fieldSelector: fields.Everything()}
+
+	switch {
+	case resource == "":
+		return []podSource{all}, nil
+	case g.options.PodNameContains:
+		all.nameContains = resource
+		return []podSource{all}, nil
+	}
+
+	kindName, name, found := strings.Cut(resource, "/")
+	if !found {
+		kindName, name = "pod", resource
+	}
+
+	kind, ok := findWorkloadKind(kindName)
+	if !ok {
+		return nil, fmt.Errorf("unsupported resource kind %q: must be one of pod, deployment, statefulset, daemonset, replicaset, job, cronjob, service", kindName)
+	}
+	if name == "" {
+		return nil, fmt.Errorf("invalid resource %q: empty name", resource)
+	}
+
+	if kind.name == "pod" && follow {
+		// The pod may still be created while following.
+		return []podSource{podByName(namespace, name)}, nil
+	}
+
+	sources, err := kind.resolve(ctx, g.clientset, namespace, name)
+	if err != nil {
+		return nil, fmt.Errorf("error getting %s %q: %v", kind.name, name, err)
+	}
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("%s %q not found", kind.name, name)
+	}
+
+	return sources, nil
+}
+
+// byName lists the resources with a name.
+func byName(name string) metav1.ListOptions {
+	return metav1.ListOptions{FieldSelector: fields.OneTermEqualSelector("metadata.name", name).String()}
+}
+
+// resolvePods selects the pods with a name, if there are any. Pods are still
+// selected by name, so that they're listed once across namespaces.
+func resolvePods(ctx context.Context, clientset kubernetes.Interface, namespace, name string) ([]podSource, error) {
+	list, err := clientset.CoreV1().Pods(namespace).List(ctx, byName(name))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pod := range list.Items {
+		if pod.Name == name {
+			return []podSource{podByName(namespace, name)}, nil
+		}
+	}
+	return nil, nil
+}
+
+// podByName selects the pod with a name in a namespace, or in all namespaces
+// if it's empty.
+func podByName(namespace, name string) podSource {
+	return podSource{
+		namespace:     namespace,
+		selector:      labels.Everything(),
+		fieldSelector: fields.OneTermEqualSelector("metadata.name", name),
+	}
+}
+
+// workloadSource selects the pods of a workload with its selector, and
+// checks that they're controlled by the workload.
+func workloadSource(object metav1.Object, kind string, selector *metav1.LabelSelector, chain ...string) (podSource, error) {
+	podSelector, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return podSource{}, err
+	}
+
+	return podSource{
+		namespace:     object.GetNamespace(),
+		selector:      podSelector,
+		fieldSelector: fields.Everything(),
+		controller:    &metav1.OwnerReference{Kind: kind, Name: object.GetName(), UID: object.GetUID()},
+		chain:         chain,
+	}, nil
+}
+
+// resolveWorkloads returns a resolve function for a kind of workload, listed
+// with list. Pods are selected with the selector of each workload, and checked
+// to be controlled by it through intermediate controllers of the kinds in chain.
+func resolveWorkloads[T any, PT interface {
+	*T
+	metav1.Object
+}](kind string, list func(ctx context.Context, clientset kubernetes.Interface, namespace string, options metav1.ListOptions) ([]T, error), selector func(PT) *metav1.LabelSelector, chain ...string) resolveFunc {
+	return func(ctx context.Context, clientset kubernetes.Interface, namespace, name string) ([]podSource, error) {
+		workloads, err := list(ctx, clientset, namespace, byName(name))
+		if err != nil {
+			return nil, err
+		}
+
+		var sources []podSource
+		for i := range workloads {
+			workload := PT(&workloads[i])
+			if workload.GetName() != name {
+				continue
+			}
+			source, err := workloadSource(workload, kind, selector(workload), chain...)
+			if err != nil {
+				return nil, err
+			}
+			sources = append(sources, source)
+		}
+		return sources, nil
+	}
+}
+
+func listDeployments(ctx context.Context, clientset kubernetes.Interface, namespace string, options metav1.ListOptions) ([]appsv1.Deployment, error) {
+	list, err := clientset.AppsV1().Deployments(namespace).List(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+func listStatefulSets(ctx context.Context, clientset kubernetes.Interface, namespace string, options metav1.ListOptions) ([]appsv1.StatefulSet, error) {
+	list, err := clientset.AppsV1().StatefulSets(namespace).List(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+func listDaemonSets(ctx context.Context, clientset kubernetes.Interface, namespace string, options metav1.ListOptions) ([]appsv1.DaemonSet, error) {
+	list, err := clientset.AppsV1().DaemonSets(namespace).List(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+func listReplicaSets(ctx context.Context, clientset kubernetes.Interface, namespace string, options metav1.ListOptions) ([]appsv1.ReplicaSet, error) {
+	list, err := clientset.AppsV1().ReplicaSets(namespace).List(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+func listJobs(ctx context.Context, clientset kubernetes.Interface, namespace string, options metav1.ListOptions) ([]batchv1.Job, error) {
+	list, err := clientset.BatchV1().Jobs(namespace).List(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+func listCronJobs(ctx context.Context, clientset kubernetes.Interface, namespace string, options metav1.ListOptions) ([]batchv1.CronJob, error) {
+	list, err := clientset.BatchV1().CronJobs(namespace).List(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+// resolveServices selects the pods of services with their selector. Services
+// don't own pods.
+func resolveServices(ctx context.Context, clientset kubernetes.Interface, namespace, name string) ([]podSource, error) {
+	list, err := clientset.CoreV1().Services(namespace).List(ctx, byName(name))
+	if err != nil {
+		return nil, err
+	}
+
+	var sources []podSource
+	for _, service := range list.Items {
+		if service.Name != name {
+			continue
+		}
+		if len(service.Spec.Selector) == 0 {
+			return nil, fmt.Errorf("service %s/%s has no selector", service.Namespace, service.Name)
+		}
+		sources = append(sources, podSource{
+			namespace:     service.Namespace,
+			selector:      labels.SelectorFromSet(service.Spec.Selector),
+			fieldSelector: fields.Everything(),
+		})
+	}
+	return sources, nil
+}
+
+// podFilter checks that pods belong to the sources they were listed from.
+// The intermediate controllers of pods are cached, so it isn't safe for
+// concurrent use.
+type podFilter struct {
+	clientset kubernetes.Interface
+	sources   []podSource
+	// controllers caches the controllers of intermediate controllers, keyed by
+	// kind, namespace and name. Controllers that can't be found are nil.
+	controllers map[string]*metav1.OwnerReference
+}
+
+func (g *Grepper) newPodFilter(ctx context.Context, namespace, resource string, follow bool) (*podFilter, error) {
+	sources, err := g.podSources(ctx, namespace, resource, follow)
+	if err != nil {
+		return nil, err
+	}
+
+	return &podFilter{
+		clientset:   g.clientset,
+		sources:     sources,
+		controllers: make(map[string]*metav1.OwnerReference),
+	}, nil
+}
+
+// matches reports whether a pod belongs to any source.
+func (f *podFilter) matches(ctx context.Context, pod *corev1.Pod) bool {
+	for _, source := range f.sources {
+		if (source.namespace == "" || source.namespace == pod.Namespace) && f.selects(ctx, source, pod) {
+			return true
+		}
+	}
+	return false
+}
+
+// selects reports whether a pod listed from a source belongs to it.
+func (f *podFilter) selects(ctx context.Context, source podSource, pod *corev1.Pod) bool {
+	if !strings.Contains(pod.Name, source.nameContains) {
+		return false
+	}
+	if !source.selector.Matches(labels.Set(pod.Labels)) || !matchesPodFields(source.fieldSelector, pod) {
+		return false
+	}
+	if source.controller == nil {
+		return true
+	}
+
+	controller := metav1.GetControllerOf(pod)
+	for _, kind := range source.chain {
+		if controller == nil || controller.Kind != kind {
+			return false
+		}
+		controller = f.controllerOf(ctx, kind, pod.Namespace, controller.Name)
+	}
+	return controller != nil && controller.Kind == source.controller.Kind && controller.UID == source.controller.UID
+}
+
+// controllerOf returns the controller of an intermediate controller, such as
+// the deployment of a replica set.
+func (f *podFilter) controllerOf(ctx context.Context, kind, namespace, name string) *metav1.OwnerReference {
+	key := kind + "/" + namespace + "/" + name
+	if controller, found := f.controllers[key]; found {
+		return controller
+	}
+
+	var object metav1.Object
+	var err error
+	switch kind {
+	case "ReplicaSet":
+		object, err = f.clientset.AppsV1().ReplicaSets(namespace).Get(ctx, name, metav1.GetOptions{})
+	case "Job":
+		object, err = f.clientset.BatchV1().Jobs(namespace).Get(ctx, name, metav1.GetOptions{})
+	default:
+		err = fmt.Errorf("unsupported controller kind %q", kind)
+	}
+	if err != nil {
+		// Controllers deleted since are cached as missing too.
+		f.controllers[key] = nil
+		return nil
+	}
+
+	controller := metav1.GetControllerOfNoCopy(object)
+	f.controllers[key] = controller
+	return controller
+}
+
+// matchesPodFields reports whether a pod satisfies the requirements of a field
+// selector on the fields in podFields. Requirements on other fields, e.g.
+// status.podIPs, were already applied by the API server the pod was listed from.
+func matchesPodFields(selector fields.Selector, pod *corev1.Pod) bool {
+	podFieldSet := podFields(pod)
+	// Requirements transformed to an empty field and value are dropped.
+	known, _ := selector.Transform(func(field, value string) (string, string, error) {
+		if !podFieldSet.Has(field) {
+			return "", "", nil
+		}
+		return field, value, nil
+	})
+	return known.Matches(podFieldSet)
+}
+
+// podFields returns the fields of a pod that field selectors can select, as
+// the API server does.
+func podFields(pod *corev1.Pod) fields.Set {
+	return fields.Set{
+		"metadata.name":            pod.Name,
+		"metadata.namespace":       pod.Namespace,
+		"spec.nodeName":            pod.Spec.NodeName,
+		"spec.restartPolicy":       string(pod.Spec.RestartPolicy),
+		"spec.schedulerName":       pod.Spec.SchedulerName,
+		"spec.serviceAccountName":  pod.Spec.ServiceAccountName,
+		"spec.hostNetwork":         strconv.FormatBool(pod.Spec.HostNetwork),
+		"status.phase":             string(pod.Status.Phase),
+		"status.podIP":             podIP(pod),
+		"status.nominatedNodeName": pod.Status.NominatedNodeName,
+	}
+}
+
+// podIP returns the primary IP of a pod, which the API server selects as
+// status.podIP.
+func podIP(pod *corev1.Pod) string {
+	if len(pod.Status.PodIPs) > 0 {
+		return pod.Status.PodIPs[0].IP
+	}
+	return pod.Status.PodIP
+}
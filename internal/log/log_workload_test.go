@@ -0,0 +1,249 @@
+package log
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func controlledBy(kind, name string, uid types.UID) []metav1.OwnerReference {
+	controller := true
+	return []metav1.OwnerReference{{Kind: kind, Name: name, UID: uid, Controller: &controller}}
+}
+
+func ownedPod(name string, labels map[string]string, owners []metav1.OwnerReference) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test", Labels: labels, OwnerReferences: owners},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+	}
+}
+
+// workloadObjects returns a cluster where the pods of several workloads have
+// overlapping names and labels.
+func workloadObjects() []runtime.Object {
+	api := map[string]string{"app": "api"}
+	report := map[string]string{"batch.kubernetes.io/controller-uid": "job-report"}
+	return []runtime.Object{
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "test", UID: "deploy-api"},
+			Spec:       appsv1.DeploymentSpec{Selector: &metav1.LabelSelector{MatchLabels: api}},
+		},
+		&appsv1.ReplicaSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "api-5d8f", Namespace: "test", UID: "rs-api", OwnerReferences: controlledBy("Deployment", "api", "deploy-api")},
+		},
+		&appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "api-cache", Namespace: "test", UID: "sts-api-cache"},
+			Spec:       appsv1.StatefulSetSpec{Selector: &metav1.LabelSelector{MatchLabels: api}},
+		},
+		&batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{Name: "report", Namespace: "test", UID: "cj-report"}},
+		&batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: "report-2901", Namespace: "test", UID: "job-report", OwnerReferences: controlledBy("CronJob", "report", "cj-report")},
+			Spec:       batchv1.JobSpec{Selector: &metav1.LabelSelector{MatchLabels: report}},
+		},
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "test"},
+			Spec:       corev1.ServiceSpec{Selector: api},
+		},
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "external", Namespace: "test"}},
+
+		ownedPod("api-5d8f-x1", api, controlledBy("ReplicaSet", "api-5d8f", "rs-api")),
+		ownedPod("api-cache-0", api, controlledBy("StatefulSet", "api-cache", "sts-api-cache")),
+		ownedPod("api-gateway-7c2b", map[string]string{"app": "gateway"}, nil),
+		ownedPod("report-2901-q8", report, controlledBy("Job", "report-2901", "job-report")),
+	}
+}
+
+func TestLogGrepper_Grep_Workloads(t *testing.T) {
+	testCases := []struct {
+		resource        string
+		podNameContains bool
+		expected        []string
+	}{
+		{resource: "deployment/api", expected: []string{"api-5d8f-x1"}},
+		{resource: "deploy/api", expected: []string{"api-5d8f-x1"}},
+		{resource: "sts/api-cache", expected: []string{"api-cache-0"}},
+		{resource: "cronjob/report", expected: []string{"report-2901-q8"}},
+		{resource: "job/report-2901", expected: []string{"report-2901-q8"}},
+		{resource: "service/api", expected: []string{"api-5d8f-x1", "api-cache-0"}},
+		{resource: "pod/api-gateway-7c2b", expected: []string{"api-gateway-7c2b"}},
+		{resource: "api-gateway-7c2b", expected: []string{"api-gateway-7c2b"}},
+		{resource: "api", podNameContains: true, expected: []string{"api-5d8f-x1", "api-cache-0", "api-gateway-7c2b"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.resource, func(t *testing.T) {
+			fakeLogReader := newFakeLogReader()
+			for _, pod := range []string{"api-5d8f-x1", "api-cache-0", "api-gateway-7c2b", "report-2901-q8"} {
+				fakeLogReader.addLog("test", pod, "app", "error")
+			}
+
+			grepper := &Grepper{
+				clientset: fake.NewClientset(workloadObjects()...),
+				logReader: fakeLogReader,
+				options:   Options{PodNameContains: tc.podNameContains},
+			}
+
+			messages, err := grepper.Grep("test", tc.resource, newMatcher(t, "error"), "POD_AND_CONTAINER")
+			require.NoError(t, err)
+
+			var pods []string
+			for _, message := range messages {
+				pods = append(pods, message.PodName)
+			}
+			assert.Equal(t, tc.expected, pods)
+		})
+	}
+}
+
+func TestLogGrepper_Grep_WorkloadErrors(t *testing.T) {
+	testCases := []struct {
+		resource string
+		expected string
+	}{
+		{resource: "deployment/missing", expected: `deployment "missing" not found`},
+		{resource: "pod/missing", expected: `pod "missing" not found`},
+		{resource: "api", expected: `pod "api" not found`},
+		{resource: "configmap/api", expected: `unsupported resource kind "configmap"`},
+		{resource: "deployment/", expected: `invalid resource "deployment/": empty name`},
+		{resource: "service/external", expected: "service test/external has no selector"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.resource, func(t *testing.T) {
+			grepper := &Grepper{clientset: fake.NewClientset(workloadObjects()...), logReader: newFakeLogReader()}
+
+			_, err := grepper.Grep("test", tc.resource, newMatcher(t, "error"), "")
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.expected)
+		})
+	}
+}
+
+func TestLogGrepper_Grep_PodSelectors(t *testing.T) {
+	pod := func(name, tier string, phase corev1.PodPhase, node string) runtime.Object {
+		p := ownedPod(name, map[string]string{"app": "checkout", "tier": tier}, nil)
+		p.Spec.NodeName = node
+		p.Status.Phase = phase
+		return p
+	}
+
+	fakeLogReader := newFakeLogReader()
+	for _, name := range []string{"web-1", "web-2", "web-3", "web-4"} {
+		fakeLogReader.addLog("test", name, "app", "panic")
+	}
+
+	fakeClientset := fake.NewClientset(
+		pod("web-1", "backend", corev1.PodRunning, "node-a"),
+		pod("web-2", "frontend", corev1.PodRunning, "node-a"),
+		pod("web-3", "backend", corev1.PodPending, "node-a"),
+		pod("web-4", "backend", corev1.PodRunning, "node-b"),
+	)
+	grepper := &Grepper{
+		clientset: fakeClientset,
+		logReader: fakeLogReader,
+		options: Options{
+			LabelSelector: "app=checkout,tier=backend",
+			FieldSelector: "metadata.name!=web-9",
+			Phase:         corev1.PodRunning,
+			NodeName:      "node-a",
+		},
+	}
+
+	messages, err := grepper.Grep("test", "", newMatcher(t, "panic"), "")
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.Equal(t, "web-1", messages[0].PodName)
+
+	// The selectors are passed to the API server.
+	var restrictions []k8stesting.ListRestrictions
+	for _, action := range fakeClientset.Actions() {
+		if list, ok := action.(k8stesting.ListAction); ok {
+			restrictions = append(restrictions, list.GetListRestrictions())
+		}
+	}
+	require.Len(t, restrictions, 1)
+	assert.Equal(t, "app=checkout,tier=backend", restrictions[0].Labels.String())
+	assert.Equal(t, "metadata.name!=web-9,spec.nodeName=node-a,status.phase=Running", restrictions[0].Fields.String())
+}
+
+func TestLogGrepper_Grep_PodSelectorsWithWorkload(t *testing.T) {
+	fakeLogReader := newFakeLogReader()
+	fakeLogReader.addLog("test", "api-5d8f-x1", "app", "error")
+
+	fakeClientset := fake.NewClientset(workloadObjects()...)
+	grepper := &Grepper{
+		clientset: fakeClientset,
+		logReader: fakeLogReader,
+		options:   Options{LabelSelector: "track=canary"},
+	}
+
+	messages, err := grepper.Grep("test", "deployment/api", newMatcher(t, "error"), "")
+	require.NoError(t, err)
+	assert.Empty(t, messages, "pods must be selected by both the workload and the label selector")
+
+	for _, action := range fakeClientset.Actions() {
+		if list, ok := action.(k8stesting.ListAction); ok && action.GetResource().Resource == "pods" {
+			assert.Equal(t, "app=api,track=canary", list.GetListRestrictions().Labels.String())
+		}
+	}
+}
+
+func TestLogGrepper_Grep_InvalidPodSelectors(t *testing.T) {
+	grepper := &Grepper{clientset: fake.NewClientset(), logReader: newFakeLogReader(), options: Options{LabelSelector: "app in (a"}}
+
+	_, err := grepper.Grep("test", "", newMatcher(t, "error"), "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid label selector")
+}
+
+func TestMatchesPodFields(t *testing.T) {
+	pod := ownedPod("web-1", nil, nil)
+	pod.Spec.NodeName = "node-a"
+	pod.Status.PodIPs = []corev1.PodIP{{IP: "10.0.0.1"}, {IP: "fd00::1"}}
+
+	testCases := []struct {
+		selector string
+		matches  bool
+	}{
+		{selector: "spec.nodeName=node-a", matches: true},
+		{selector: "spec.nodeName=node-b", matches: false},
+		{selector: "status.podIP=10.0.0.1", matches: true},
+		// Fields the API server selects but pods can't be matched against
+		// client-side were already applied when the pods were listed.
+		{selector: "status.podIPs=fd00::1", matches: true},
+		{selector: "status.podIPs=fd00::1,spec.nodeName=node-b", matches: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.selector, func(t *testing.T) {
+			selector, err := fields.ParseSelector(tc.selector)
+			require.NoError(t, err)
+			assert.Equal(t, tc.matches, matchesPodFields(selector, pod))
+		})
+	}
+}
+
+func TestLogGrepper_NewPodFilter_FollowMissingPod(t *testing.T) {
+	grepper := &Grepper{clientset: fake.NewClientset(workloadObjects()...)}
+
+	// When following, the pod may still be created.
+	filter, err := grepper.newPodFilter(context.Background(), "test", "pod/later", true)
+	require.NoError(t, err)
+	require.Len(t, filter.sources, 1)
+	assert.Equal(t, "metadata.name=later", filter.sources[0].fieldSelector.String())
+
+	_, err = grepper.newPodFilter(context.Background(), "test", "pod/later", false)
+	require.Error(t, err)
+	assert.Equal(t, `pod "later" not found`, err.Error())
+}
@@ -0,0 +1,165 @@
+package match
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Span represents the byte range of a match within a line.
+type Span struct {
+	Start int
+	End   int
+	// Pattern is the pattern that produced the match.
+	Pattern string
+}
+
+// Matcher reports whether a line matches and which parts of it matched.
+// A matching line may have no spans, e.g. when the pattern is empty.
+type Matcher interface {
+	Match(line string) ([]Span, bool)
+}
+
+// Options controls how a pattern is interpreted.
+type Options struct {
+	// Regexp interprets the pattern as an RE2 regular expression instead of a fixed string.
+	Regexp bool
+	// CaseSensitive disables the default case-insensitive matching.
+	CaseSensitive bool
+	// WholeWord only accepts matches that form whole words.
+	WholeWord bool
+}
+
+// New compiles a pattern into a Matcher according to the given options.
+// An empty pattern matches every line.
+func New(pattern string, opts Options) (Matcher, error) {
+	return NewSet([]string{pattern}, opts)
+}
+
+// everything matches every line without highlighting anything.
+type everything struct{}
+
+func (everything) Match(string) ([]Span, bool) {
+	return nil, true
+}
+
+// regexpMatcher matches lines against a compiled regular expression.
+// Fixed strings are compiled as quoted regular expressions. When the
+// expression combines several patterns, each of them is wrapped in a
+// capturing group so that matches can be attributed to their pattern.
+type regexpMatcher struct {
+	re        *regexp.Regexp
+	wholeWord bool
+	patterns  []string
+	// groups holds the index of the capturing group of each pattern.
+	groups []int
+}
+
+// newRegexpMatcher compiles patterns into a single regular expression.
+func newRegexpMatcher(patterns []string, opts Options) (*regexpMatcher, error) {
+	m := &regexpMatcher{wholeWord: opts.WholeWord, patterns: patterns}
+
+	var expr strings.Builder
+	if !opts.CaseSensitive {
+		expr.WriteString("(?i)")
+	}
+
+	group := 1
+	for i, pattern := range patterns {
+		if !opts.Regexp {
+			pattern = regexp.QuoteMeta(pattern)
+		}
+
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %v", patterns[i], err)
+		}
+
+		if len(patterns) == 1 {
+			expr.WriteString(pattern)
+			break
+		}
+
+		if i > 0 {
+			expr.WriteString("|")
+		}
+		expr.WriteString("(" + pattern + ")")
+		m.groups = append(m.groups, group)
+		group += 1 + re.NumSubexp()
+	}
+
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression: %v", err)
+	}
+	if len(patterns) > 1 {
+		// Prefer the longest pattern matching at a position, as the Aho-Corasick
+		// matcher does, rather than the first one, which -w could then reject
+		// as part of a longer word, e.g. "app" in "application".
+		re.Longest()
+	}
+	m.re = re
+
+	return m, nil
+}
+
+func (m *regexpMatcher) Match(line string) ([]Span, bool) {
+	locs := m.re.FindAllStringSubmatchIndex(line, -1)
+	if locs == nil {
+		return nil, false
+	}
+
+	var spans []Span
+	for _, loc := range locs {
+		start, end := loc[0], loc[1]
+		if m.wholeWord && !isWholeWord(line, start, end) {
+			continue
+		}
+		// Patterns that only match the empty string (e.g. "^") match the line without spans.
+		if end > start {
+			spans = append(spans, Span{Start: start, End: end, Pattern: m.patternOf(loc)})
+		}
+	}
+
+	if len(spans) == 0 && m.wholeWord {
+		return nil, false
+	}
+	return spans, true
+}
+
+// patternOf returns the pattern whose capturing group participated in the match.
+func (m *regexpMatcher) patternOf(loc []int) string {
+	if len(m.patterns) == 1 {
+		return m.patterns[0]
+	}
+	for i, group := range m.groups {
+		if loc[2*group] >= 0 {
+			return m.patterns[i]
+		}
+	}
+	return ""
+}
+
+// isWholeWord reports whether line[start:end] is delimited by non-word characters
+// or by the boundaries of the line, following grep's -w semantics.
+func isWholeWord(line string, start, end int) bool {
+	if start > 0 {
+		r, _ := utf8.DecodeLastRuneInString(line[:start])
+		if isWordChar(r) {
+			return false
+		}
+	}
+	if end < len(line) {
+		r, _ := utf8.DecodeRuneInString(line[end:])
+		if isWordChar(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func isWordChar(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,101 @@
+package match
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew_Modes(t *testing.T) {
+	testCases := []struct {
+		name    string
+		pattern string
+		opts    Options
+		line    string
+		matched bool
+		spans   []Span
+	}{
+		{
+			name:    "fixed string is case-insensitive by default",
+			pattern: "Error",
+			line:    "an error and an ERROR",
+			matched: true,
+			spans:   []Span{{Start: 3, End: 8, Pattern: "Error"}, {Start: 16, End: 21, Pattern: "Error"}},
+		},
+		{
+			name:    "fixed string treats metacharacters literally",
+			pattern: "a.b",
+			line:    "axb a.b",
+			matched: true,
+			spans:   []Span{{Start: 4, End: 7, Pattern: "a.b"}},
+		},
+		{
+			name:    "case-sensitive fixed string",
+			pattern: "Error",
+			opts:    Options{CaseSensitive: true},
+			line:    "an error",
+			matched: false,
+		},
+		{
+			name:    "regular expression",
+			pattern: `image: .*:latest$`,
+			opts:    Options{Regexp: true},
+			line:    "  image: nginx:latest",
+			matched: true,
+			spans:   []Span{{Start: 2, End: 21, Pattern: `image: .*:latest$`}},
+		},
+		{
+			name:    "regular expression alternation",
+			pattern: `level=(error|fatal)`,
+			opts:    Options{Regexp: true},
+			line:    "level=warn",
+			matched: false,
+		},
+		{
+			name:    "whole word skips partial words",
+			pattern: "app",
+			opts:    Options{WholeWord: true},
+			line:    "application app",
+			matched: true,
+			spans:   []Span{{Start: 12, End: 15, Pattern: "app"}},
+		},
+		{
+			name:    "whole word rejects embedded matches",
+			pattern: "app",
+			opts:    Options{WholeWord: true},
+			line:    "my_app",
+			matched: false,
+		},
+		{
+			name:    "empty pattern matches everything",
+			pattern: "",
+			line:    "anything",
+			matched: true,
+		},
+		{
+			name:    "zero-width regular expression matches without spans",
+			pattern: "^",
+			opts:    Options{Regexp: true},
+			line:    "anything",
+			matched: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			matcher, err := New(tc.pattern, tc.opts)
+			require.NoError(t, err)
+
+			spans, matched := matcher.Match(tc.line)
+			assert.Equal(t, tc.matched, matched)
+			assert.Equal(t, tc.spans, spans)
+		})
+	}
+}
+
+func TestNew_InvalidRegexp(t *testing.T) {
+	_, err := New("(unclosed", Options{Regexp: true})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid regular expression")
+}
@@ -0,0 +1,452 @@
+package match
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Query is a Matcher combining patterns with boolean operators.
+//
+// The query language supports the AND, OR and NOT operators (in upper case),
+// parentheses for grouping and double-quoted phrases. Adjacent unquoted words
+// form a single phrase, and operands placed next to each other without an
+// operator are combined with AND. For example:
+//
+//	timeout AND NOT healthz
+//	(postgres OR mysql) password
+//	"connection refused" OR "i/o timeout"
+type Query struct {
+	root node
+}
+
+// LineMatch describes a matching line of a document.
+type LineMatch struct {
+	Line  int
+	Spans []Span
+}
+
+// ParseQuery parses a boolean query. Every pattern in the query is compiled
+// with New using the given options.
+func ParseQuery(query string, opts Options) (*Query, error) {
+	tokens, err := tokenize(query)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{query: query, tokens: tokens, opts: opts}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty query")
+	}
+
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.done() {
+		return nil, fmt.Errorf("unexpected %s in query %q", p.peek(), query)
+	}
+
+	return &Query{root: root}, nil
+}
+
+// Any returns a Matcher that matches when any of the given matchers match.
+func Any(matchers ...Matcher) Matcher {
+	if len(matchers) == 1 {
+		return matchers[0]
+	}
+
+	var or orNode
+	for _, matcher := range matchers {
+		or = append(or, toNode(matcher))
+	}
+	return &Query{root: or}
+}
+
+// Not returns a Matcher that matches when the given matcher does not.
+func Not(matcher Matcher) Matcher {
+	return &Query{root: notNode{toNode(matcher)}}
+}
+
+// Match evaluates the query against a single line. The returned spans are the
+// matches of all patterns that are not negated.
+func (q *Query) Match(line string) ([]Span, bool) {
+	results := map[*term]result{}
+	resultOf := func(t *term) result {
+		r, found := results[t]
+		if !found {
+			r.spans, r.matched = t.matcher.Match(line)
+			results[t] = r
+		}
+		return r
+	}
+
+	if !q.root.eval(func(t *term) bool { return resultOf(t).matched }) {
+		return nil, false
+	}
+
+	var spans []Span
+	q.root.positiveTerms(false, func(t *term) {
+		spans = append(spans, resultOf(t).spans...)
+	})
+
+	return sortSpans(spans), true
+}
+
+// MatchDocument evaluates a matcher against a document as a whole: every
+// pattern matches the document if it matches any of its lines. When the
+// document matches, the lines matched by patterns that are not negated are
+// returned.
+func MatchDocument(matcher Matcher, lines []string) ([]LineMatch, bool) {
+	q := toQuery(matcher)
+
+	results := map[*term][]result{}
+	resultsOf := func(t *term) []result {
+		r, found := results[t]
+		if !found {
+			r = make([]result, len(lines))
+			for i, line := range lines {
+				r[i].spans, r[i].matched = t.matcher.Match(line)
+			}
+			results[t] = r
+		}
+		return r
+	}
+
+	matchesDocument := func(t *term) bool {
+		for _, r := range resultsOf(t) {
+			if r.matched {
+				return true
+			}
+		}
+		return false
+	}
+
+	if !q.root.eval(matchesDocument) {
+		return nil, false
+	}
+
+	matched := make([]bool, len(lines))
+	spans := make([][]Span, len(lines))
+	q.root.positiveTerms(false, func(t *term) {
+		for i, r := range resultsOf(t) {
+			if r.matched {
+				matched[i] = true
+				spans[i] = append(spans[i], r.spans...)
+			}
+		}
+	})
+
+	var lineMatches []LineMatch
+	for i := range lines {
+		if matched[i] {
+			lineMatches = append(lineMatches, LineMatch{Line: i, Spans: sortSpans(spans[i])})
+		}
+	}
+
+	return lineMatches, true
+}
+
+type result struct {
+	spans   []Span
+	matched bool
+}
+
+// node is an element of a query tree.
+type node interface {
+	// eval evaluates the node, using matched to decide whether a term matches.
+	eval(matched func(*term) bool) bool
+	// positiveTerms calls fn for every term that is not negated.
+	positiveTerms(negated bool, fn func(*term))
+}
+
+type term struct {
+	matcher Matcher
+}
+
+func (t *term) eval(matched func(*term) bool) bool {
+	return matched(t)
+}
+
+func (t *term) positiveTerms(negated bool, fn func(*term)) {
+	if !negated {
+		fn(t)
+	}
+}
+
+type andNode []node
+
+func (n andNode) eval(matched func(*term) bool) bool {
+	for _, child := range n {
+		if !child.eval(matched) {
+			return false
+		}
+	}
+	return true
+}
+
+func (n andNode) positiveTerms(negated bool, fn func(*term)) {
+	for _, child := range n {
+		child.positiveTerms(negated, fn)
+	}
+}
+
+type orNode []node
+
+func (n orNode) eval(matched func(*term) bool) bool {
+	for _, child := range n {
+		if child.eval(matched) {
+			return true
+		}
+	}
+	return false
+}
+
+func (n orNode) positiveTerms(negated bool, fn func(*term)) {
+	for _, child := range n {
+		child.positiveTerms(negated, fn)
+	}
+}
+
+type notNode struct {
+	node
+}
+
+func (n notNode) eval(matched func(*term) bool) bool {
+	return !n.node.eval(matched)
+}
+
+func (n notNode) positiveTerms(negated bool, fn func(*term)) {
+	n.node.positiveTerms(!negated, fn)
+}
+
+func toNode(matcher Matcher) node {
+	if q, ok := matcher.(*Query); ok {
+		return q.root
+	}
+	return &term{matcher: matcher}
+}
+
+func toQuery(matcher Matcher) *Query {
+	if q, ok := matcher.(*Query); ok {
+		return q
+	}
+	return &Query{root: &term{matcher: matcher}}
+}
+
+// sortSpans sorts spans by position. Overlapping spans produced by different
+// patterns are kept so that every pattern is reported.
+func sortSpans(spans []Span) []Span {
+	sort.SliceStable(spans, func(i, j int) bool {
+		if spans[i].Start != spans[j].Start {
+			return spans[i].Start < spans[j].Start
+		}
+		return spans[i].End > spans[j].End
+	})
+	return spans
+}
+
+type tokenKind int
+
+const (
+	tokenWord tokenKind = iota
+	tokenPhrase
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenOpen
+	tokenClose
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	start int
+	end   int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokenOpen:
+		return "'('"
+	case tokenClose:
+		return "')'"
+	default:
+		return fmt.Sprintf("%q", t.text)
+	}
+}
+
+func tokenize(query string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenOpen, text: "(", start: i, end: i + 1})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenClose, text: ")", start: i, end: i + 1})
+			i++
+		case c == '"':
+			var phrase strings.Builder
+			j := i + 1
+			for ; j < len(query) && query[j] != '"'; j++ {
+				if query[j] == '\\' && j+1 < len(query) {
+					j++
+				}
+				phrase.WriteByte(query[j])
+			}
+			if j >= len(query) {
+				return nil, fmt.Errorf("unterminated quoted phrase in query %q", query)
+			}
+			tokens = append(tokens, token{kind: tokenPhrase, text: phrase.String(), start: i, end: j + 1})
+			i = j + 1
+		default:
+			j := i
+			for j < len(query) && !unicode.IsSpace(rune(query[j])) && !strings.ContainsRune(`()"`, rune(query[j])) {
+				j++
+			}
+			word := query[i:j]
+			kind := tokenWord
+			switch word {
+			case "AND":
+				kind = tokenAnd
+			case "OR":
+				kind = tokenOr
+			case "NOT":
+				kind = tokenNot
+			}
+			tokens = append(tokens, token{kind: kind, text: word, start: i, end: j})
+			i = j
+		}
+	}
+	return tokens, nil
+}
+
+type parser struct {
+	query  string
+	tokens []token
+	pos    int
+	opts   Options
+}
+
+func (p *parser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	or := orNode{left}
+	for !p.done() && p.peek().kind == tokenOr {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		or = append(or, right)
+	}
+
+	if len(or) == 1 {
+		return left, nil
+	}
+	return or, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	and := andNode{left}
+	for !p.done() {
+		switch p.peek().kind {
+		case tokenAnd:
+			p.pos++
+		case tokenWord, tokenPhrase, tokenNot, tokenOpen:
+			// Operands next to each other are implicitly combined with AND.
+		default:
+			if len(and) == 1 {
+				return left, nil
+			}
+			return and, nil
+		}
+
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		and = append(and, right)
+	}
+
+	if len(and) == 1 {
+		return left, nil
+	}
+	return and, nil
+}
+
+func (p *parser) parseNot() (node, error) {
+	if !p.done() && p.peek().kind == tokenNot {
+		p.pos++
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	if p.done() {
+		return nil, fmt.Errorf("unexpected end of query %q", p.query)
+	}
+
+	tok := p.peek()
+	switch tok.kind {
+	case tokenOpen:
+		p.pos++
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.done() || p.peek().kind != tokenClose {
+			return nil, fmt.Errorf("missing ')' in query %q", p.query)
+		}
+		p.pos++
+		return inner, nil
+	case tokenPhrase:
+		p.pos++
+		return p.newTerm(tok.text)
+	case tokenWord:
+		// Adjacent words form a single phrase, preserving the original spacing.
+		start, end := tok.start, tok.end
+		p.pos++
+		for !p.done() && p.peek().kind == tokenWord {
+			end = p.peek().end
+			p.pos++
+		}
+		return p.newTerm(p.query[start:end])
+	default:
+		return nil, fmt.Errorf("unexpected %s in query %q", tok, p.query)
+	}
+}
+
+func (p *parser) newTerm(pattern string) (node, error) {
+	matcher, err := New(pattern, p.opts)
+	if err != nil {
+		return nil, err
+	}
+	return &term{matcher: matcher}, nil
+}
@@ -0,0 +1,144 @@
+package match
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseQuery_Match(t *testing.T) {
+	testCases := []struct {
+		name    string
+		query   string
+		line    string
+		matched bool
+		spans   []Span
+	}{
+		{
+			name:    "and with not",
+			query:   "timeout AND NOT healthz",
+			line:    "GET /api timeout",
+			matched: true,
+			spans:   []Span{{Start: 9, End: 16, Pattern: "timeout"}},
+		},
+		{
+			name:    "negated term excludes line",
+			query:   "timeout AND NOT healthz",
+			line:    "GET /healthz timeout",
+			matched: false,
+		},
+		{
+			name:    "or highlights every matching term",
+			query:   "error OR fatal",
+			line:    "fatal error",
+			matched: true,
+			spans:   []Span{{Start: 0, End: 5, Pattern: "fatal"}, {Start: 6, End: 11, Pattern: "error"}},
+		},
+		{
+			name:    "implicit and between groups",
+			query:   "(postgres OR mysql) password",
+			line:    "mysql password=secret",
+			matched: true,
+			spans:   []Span{{Start: 0, End: 5, Pattern: "mysql"}, {Start: 6, End: 14, Pattern: "password"}},
+		},
+		{
+			name:    "adjacent words form a phrase",
+			query:   "replicas: 3",
+			line:    "3 replicas: 1",
+			matched: false,
+		},
+		{
+			name:    "quoted phrase keeps operators literal",
+			query:   `"NOT FOUND"`,
+			line:    "status: NOT FOUND",
+			matched: true,
+			spans:   []Span{{Start: 8, End: 17, Pattern: "NOT FOUND"}},
+		},
+		{
+			name:    "not only query",
+			query:   "NOT healthz",
+			line:    "GET /api",
+			matched: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			query, err := ParseQuery(tc.query, Options{})
+			require.NoError(t, err)
+
+			spans, matched := query.Match(tc.line)
+			assert.Equal(t, tc.matched, matched)
+			assert.Equal(t, tc.spans, spans)
+		})
+	}
+}
+
+func TestParseQuery_Errors(t *testing.T) {
+	testCases := []struct {
+		query    string
+		expected string
+	}{
+		{query: "", expected: "empty query"},
+		{query: "(a OR b", expected: "missing ')'"},
+		{query: "a OR", expected: "unexpected end of query"},
+		{query: "a )", expected: "unexpected ')'"},
+		{query: `"unterminated`, expected: "unterminated quoted phrase"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.query, func(t *testing.T) {
+			_, err := ParseQuery(tc.query, Options{})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.expected)
+		})
+	}
+}
+
+func TestAnyAndNot(t *testing.T) {
+	foo, err := New("foo", Options{})
+	require.NoError(t, err)
+	bar, err := New("bar", Options{})
+	require.NoError(t, err)
+
+	_, matched := Any(foo, bar).Match("only bar here")
+	assert.True(t, matched)
+
+	_, matched = Not(Any(foo, bar)).Match("only bar here")
+	assert.False(t, matched)
+
+	spans, matched := Not(foo).Match("nothing")
+	assert.True(t, matched)
+	assert.Empty(t, spans)
+}
+
+func TestMatchDocument(t *testing.T) {
+	lines := []string{
+		"kind: Secret",
+		"  host: postgres.local",
+		"  password: hunter2",
+	}
+
+	query, err := ParseQuery("postgres AND password", Options{})
+	require.NoError(t, err)
+
+	_, matched := query.Match(lines[1])
+	assert.False(t, matched, "no single line contains both terms")
+
+	lineMatches, matched := MatchDocument(query, lines)
+	require.True(t, matched)
+	assert.Equal(t, []LineMatch{
+		{Line: 1, Spans: []Span{{Start: 8, End: 16, Pattern: "postgres"}}},
+		{Line: 2, Spans: []Span{{Start: 2, End: 10, Pattern: "password"}}},
+	}, lineMatches)
+
+	negated, err := ParseQuery("password AND NOT mysql", Options{})
+	require.NoError(t, err)
+	lineMatches, matched = MatchDocument(negated, lines)
+	require.True(t, matched)
+	assert.Equal(t, []LineMatch{{Line: 2, Spans: []Span{{Start: 2, End: 10, Pattern: "password"}}}}, lineMatches)
+
+	_, matched = MatchDocument(Not(query), lines)
+	assert.False(t, matched)
+}
@@ -0,0 +1,173 @@
+package match
+
+import (
+	"sort"
+	"unicode/utf8"
+)
+
+// NewSet compiles several patterns into a single Matcher that scans each line
+// once regardless of the number of patterns. Fixed strings are matched with an
+// Aho-Corasick automaton and regular expressions are combined into a single
+// alternation. Every span reports the pattern that produced it.
+// If any pattern is empty, the Matcher matches every line.
+func NewSet(patterns []string, opts Options) (Matcher, error) {
+	for _, pattern := range patterns {
+		if pattern == "" {
+			return everything{}, nil
+		}
+	}
+
+	if opts.Regexp || len(patterns) == 1 || (!opts.CaseSensitive && !allASCII(patterns)) {
+		// The automaton only folds ASCII letters, so case-insensitive
+		// non-ASCII patterns are handled by the regular expression engine.
+		return newRegexpMatcher(patterns, opts)
+	}
+
+	return newAhoCorasick(patterns, opts), nil
+}
+
+// ahoCorasick matches a set of fixed strings in a single pass over a line.
+type ahoCorasick struct {
+	patterns      []string
+	nodes         []acNode
+	caseSensitive bool
+	wholeWord     bool
+}
+
+type acNode struct {
+	next map[byte]int
+	fail int
+	// outputs holds the indexes of the patterns ending at this node,
+	// including those reachable through failure links.
+	outputs []int
+}
+
+func newAhoCorasick(patterns []string, opts Options) *ahoCorasick {
+	ac := &ahoCorasick{
+		patterns:      patterns,
+		nodes:         []acNode{{next: map[byte]int{}}},
+		caseSensitive: opts.CaseSensitive,
+		wholeWord:     opts.WholeWord,
+	}
+
+	for i, pattern := range patterns {
+		state := 0
+		for j := 0; j < len(pattern); j++ {
+			b := ac.fold(pattern[j])
+			next, found := ac.nodes[state].next[b]
+			if !found {
+				next = len(ac.nodes)
+				ac.nodes = append(ac.nodes, acNode{next: map[byte]int{}})
+				ac.nodes[state].next[b] = next
+			}
+			state = next
+		}
+		ac.nodes[state].outputs = append(ac.nodes[state].outputs, i)
+	}
+
+	// Compute failure links breadth-first so that the links of shallower
+	// nodes are known before they are needed.
+	queue := make([]int, 0, len(ac.nodes))
+	for _, child := range ac.nodes[0].next {
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		state := queue[0]
+		queue = queue[1:]
+
+		for b, child := range ac.nodes[state].next {
+			fail := ac.nodes[state].fail
+			for fail != 0 && !ac.hasTransition(fail, b) {
+				fail = ac.nodes[fail].fail
+			}
+			if next, found := ac.nodes[fail].next[b]; found && next != child {
+				ac.nodes[child].fail = next
+			}
+			ac.nodes[child].outputs = append(ac.nodes[child].outputs, ac.nodes[ac.nodes[child].fail].outputs...)
+			queue = append(queue, child)
+		}
+	}
+
+	return ac
+}
+
+func (ac *ahoCorasick) hasTransition(state int, b byte) bool {
+	_, found := ac.nodes[state].next[b]
+	return found
+}
+
+func (ac *ahoCorasick) fold(b byte) byte {
+	if !ac.caseSensitive && 'A' <= b && b <= 'Z' {
+		return b + 'a' - 'A'
+	}
+	return b
+}
+
+func (ac *ahoCorasick) Match(line string) ([]Span, bool) {
+	var candidates []Span
+	state := 0
+	for i := 0; i < len(line); i++ {
+		b := ac.fold(line[i])
+		for state != 0 && !ac.hasTransition(state, b) {
+			state = ac.nodes[state].fail
+		}
+		if next, found := ac.nodes[state].next[b]; found {
+			state = next
+		}
+
+		for _, index := range ac.nodes[state].outputs {
+			pattern := ac.patterns[index]
+			start := i + 1 - len(pattern)
+			if ac.wholeWord && !isWholeWord(line, start, i+1) {
+				continue
+			}
+			candidates = append(candidates, Span{Start: start, End: i + 1, Pattern: pattern})
+		}
+	}
+
+	if len(candidates) == 0 {
+		return nil, false
+	}
+
+	// Report the leftmost-longest matches that don't overlap, like grep does.
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Start != candidates[j].Start {
+			return candidates[i].Start < candidates[j].Start
+		}
+		return candidates[i].End > candidates[j].End
+	})
+
+	var spans []Span
+	for _, candidate := range candidates {
+		if len(spans) > 0 && candidate.Start < spans[len(spans)-1].End {
+			continue
+		}
+		spans = append(spans, candidate)
+	}
+
+	return spans, true
+}
+
+// Patterns returns the distinct patterns that produced the spans, in order of appearance.
+func Patterns(spans []Span) []string {
+	var patterns []string
+	seen := map[string]bool{}
+	for _, span := range spans {
+		if span.Pattern != "" && !seen[span.Pattern] {
+			seen[span.Pattern] = true
+			patterns = append(patterns, span.Pattern)
+		}
+	}
+	return patterns
+}
+
+func allASCII(patterns []string) bool {
+	for _, pattern := range patterns {
+		for i := 0; i < len(pattern); i++ {
+			if pattern[i] >= utf8.RuneSelf {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,94 @@
+package match
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSet_FixedStrings(t *testing.T) {
+	matcher, err := NewSet([]string{"he", "she", "hers", "token-abc"}, Options{})
+	require.NoError(t, err)
+	assert.IsType(t, &ahoCorasick{}, matcher)
+
+	spans, matched := matcher.Match("USHERS leaked TOKEN-ABC")
+	require.True(t, matched)
+	assert.Equal(t, []Span{
+		{Start: 1, End: 4, Pattern: "she"},
+		{Start: 14, End: 23, Pattern: "token-abc"},
+	}, spans)
+	assert.Equal(t, []string{"she", "token-abc"}, Patterns(spans))
+
+	_, matched = matcher.Match("nothing to see")
+	assert.False(t, matched)
+}
+
+func TestNewSet_CaseSensitiveAndWholeWord(t *testing.T) {
+	matcher, err := NewSet([]string{"old.example.com", "legacy"}, Options{CaseSensitive: true, WholeWord: true})
+	require.NoError(t, err)
+
+	spans, matched := matcher.Match("host: old.example.com legacy_db Legacy")
+	require.True(t, matched)
+	assert.Equal(t, []Span{{Start: 6, End: 21, Pattern: "old.example.com"}}, spans)
+
+	_, matched = matcher.Match("host: notold.example.com")
+	assert.False(t, matched)
+}
+
+func TestNewSet_Regexp(t *testing.T) {
+	matcher, err := NewSet([]string{`(ghp|gho)_[a-z0-9]+`, `AKIA[0-9A-Z]{4}`}, Options{Regexp: true, CaseSensitive: true})
+	require.NoError(t, err)
+
+	spans, matched := matcher.Match("keys: AKIA1234 ghp_abc123")
+	require.True(t, matched)
+	assert.Equal(t, []Span{
+		{Start: 6, End: 14, Pattern: `AKIA[0-9A-Z]{4}`},
+		{Start: 15, End: 25, Pattern: `(ghp|gho)_[a-z0-9]+`},
+	}, spans)
+}
+
+func TestNewSet_WholeWordPrefersLongestPattern(t *testing.T) {
+	for _, opts := range []Options{{WholeWord: true}, {Regexp: true, WholeWord: true}} {
+		matcher, err := NewSet([]string{"app", "application"}, opts)
+		require.NoError(t, err)
+
+		spans, matched := matcher.Match("application started")
+		require.True(t, matched, "regexp: %v", opts.Regexp)
+		assert.Equal(t, []Span{{Start: 0, End: 11, Pattern: "application"}}, spans)
+	}
+}
+
+func TestNewSet_NonASCIIFallsBackToRegexp(t *testing.T) {
+	matcher, err := NewSet([]string{"ÉCHEC", "erreur"}, Options{})
+	require.NoError(t, err)
+	assert.IsType(t, &regexpMatcher{}, matcher)
+
+	spans, matched := matcher.Match("statut: échec")
+	require.True(t, matched)
+	assert.Equal(t, []Span{{Start: 8, End: 14, Pattern: "ÉCHEC"}}, spans)
+}
+
+func TestNewSet_EmptyPatternMatchesEverything(t *testing.T) {
+	matcher, err := NewSet([]string{"foo", ""}, Options{})
+	require.NoError(t, err)
+
+	_, matched := matcher.Match("anything")
+	assert.True(t, matched)
+}
+
+func BenchmarkNewSet_FixedStrings(b *testing.B) {
+	var patterns []string
+	for i := 0; i < 500; i++ {
+		patterns = append(patterns, fmt.Sprintf("deprecated-host-%d.example.com", i))
+	}
+	matcher, err := NewSet(patterns, Options{})
+	require.NoError(b, err)
+
+	line := "    url: https://deprecated-host-499.example.com/api/v1?timeout=30s&retries=5"
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		matcher.Match(line)
+	}
+}
@@ -0,0 +1,78 @@
+// Package pager lists Kubernetes objects in chunks, so that large collections
+// are processed as they arrive instead of being held in a single response.
+package pager
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// maxRestarts limits how many times a list is restarted after its continue
+// token expires, so that a collection changing faster than it can be listed
+// doesn't restart forever.
+const maxRestarts = 3
+
+// ListFunc lists a page of objects and returns the token to continue the list
+// from, which is empty on the last page.
+type ListFunc[T any] func(ctx context.Context, options metav1.ListOptions) ([]T, string, error)
+
+// Pages lists objects in pages of at most options.Limit objects and calls visit
+// with each page as it arrives. A Limit of 0 lists all objects in one page.
+//
+// Continue tokens expire after a few minutes, e.g. while a slow consumer is
+// processing a page. The list is then restarted from the beginning and objects
+// already visited, identified by key, are skipped.
+func Pages[T any](ctx context.Context, options metav1.ListOptions, list ListFunc[T], key func(T) string, visit func([]T) error) error {
+	var visited map[string]bool
+	restarts := 0
+
+	for {
+		items, next, err := list(ctx, options)
+		if err != nil && options.Continue != "" && (apierrors.IsResourceExpired(err) || apierrors.IsGone(err)) {
+			if restarts == maxRestarts {
+				return fmt.Errorf("list restarted %d times after its continue token expired: %v", restarts, err)
+			}
+			restarts++
+			options.Continue = ""
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if options.Limit > 0 {
+			items, visited = skipVisited(items, visited, key)
+		}
+
+		if err := visit(items); err != nil {
+			return err
+		}
+
+		if next == "" {
+			return nil
+		}
+		options.Continue = next
+	}
+}
+
+// skipVisited removes the objects that were already visited and records the
+// remaining ones. Only keys are kept, not the objects themselves.
+func skipVisited[T any](items []T, visited map[string]bool, key func(T) string) ([]T, map[string]bool) {
+	if visited == nil {
+		visited = make(map[string]bool)
+	}
+
+	fresh := make([]T, 0, len(items))
+	for _, item := range items {
+		k := key(item)
+		if visited[k] {
+			continue
+		}
+		visited[k] = true
+		fresh = append(fresh, item)
+	}
+	return fresh, visited
+}
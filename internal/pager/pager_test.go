@@ -0,0 +1,117 @@
+package pager
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// fakeList pages through items, using the index of the next item as the
+// continue token. Tokens in expired are rejected once.
+type fakeList struct {
+	items   []string
+	expired map[string]bool
+	calls   []metav1.ListOptions
+}
+
+func (f *fakeList) list(_ context.Context, options metav1.ListOptions) ([]string, string, error) {
+	f.calls = append(f.calls, options)
+
+	if f.expired[options.Continue] {
+		delete(f.expired, options.Continue)
+		return nil, "", apierrors.NewResourceExpired("the provided continue parameter is too old")
+	}
+
+	if options.Limit == 0 {
+		return f.items, "", nil
+	}
+
+	start := 0
+	if options.Continue != "" {
+		start, _ = strconv.Atoi(options.Continue)
+	}
+	end := min(start+int(options.Limit), len(f.items))
+
+	next := ""
+	if end < len(f.items) {
+		next = strconv.Itoa(end)
+	}
+	return f.items[start:end], next, nil
+}
+
+func identity(s string) string { return s }
+
+func collect(t *testing.T, f *fakeList, limit int64) [][]string {
+	t.Helper()
+	var pages [][]string
+	err := Pages(context.Background(), metav1.ListOptions{Limit: limit, LabelSelector: "app=web"}, f.list, identity, func(items []string) error {
+		pages = append(pages, items)
+		return nil
+	})
+	require.NoError(t, err)
+	return pages
+}
+
+func TestPages(t *testing.T) {
+	f := &fakeList{items: []string{"a", "b", "c", "d", "e"}}
+
+	pages := collect(t, f, 2)
+	assert.Equal(t, [][]string{{"a", "b"}, {"c", "d"}, {"e"}}, pages)
+	assert.Equal(t, []metav1.ListOptions{
+		{Limit: 2, LabelSelector: "app=web"},
+		{Limit: 2, LabelSelector: "app=web", Continue: "2"},
+		{Limit: 2, LabelSelector: "app=web", Continue: "4"},
+	}, f.calls)
+}
+
+func TestPages_NoLimit(t *testing.T) {
+	f := &fakeList{items: []string{"a", "b", "c"}}
+
+	assert.Equal(t, [][]string{{"a", "b", "c"}}, collect(t, f, 0))
+	assert.Len(t, f.calls, 1)
+}
+
+func TestPages_RestartsWhenContinueTokenExpires(t *testing.T) {
+	f := &fakeList{items: []string{"a", "b", "c", "d", "e"}, expired: map[string]bool{"4": true}}
+
+	pages := collect(t, f, 2)
+	assert.Equal(t, [][]string{{"a", "b"}, {"c", "d"}, {}, {}, {"e"}}, pages, "objects visited before the restart are skipped")
+	assert.Equal(t, "", f.calls[3].Continue)
+}
+
+func TestPages_GivesUpAfterRepeatedRestarts(t *testing.T) {
+	f := &fakeList{items: []string{"a", "b", "c"}}
+
+	restarts := 0
+	err := Pages(context.Background(), metav1.ListOptions{Limit: 2}, func(ctx context.Context, options metav1.ListOptions) ([]string, string, error) {
+		if options.Continue != "" {
+			restarts++
+			return nil, "", apierrors.NewResourceExpired("the provided continue parameter is too old")
+		}
+		return f.list(ctx, options)
+	}, identity, func([]string) error { return nil })
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "list restarted 3 times")
+	assert.Equal(t, maxRestarts+1, restarts)
+}
+
+func TestPages_Errors(t *testing.T) {
+	listErr := fmt.Errorf("connection refused")
+	err := Pages(context.Background(), metav1.ListOptions{Limit: 2}, func(context.Context, metav1.ListOptions) ([]string, string, error) {
+		return nil, "", listErr
+	}, identity, func([]string) error { return nil })
+	assert.Equal(t, listErr, err)
+
+	visitErr := fmt.Errorf("stop")
+	f := &fakeList{items: []string{"a", "b", "c"}}
+	err = Pages(context.Background(), metav1.ListOptions{Limit: 2}, f.list, identity, func([]string) error { return visitErr })
+	assert.Equal(t, visitErr, err)
+	assert.Len(t, f.calls, 1)
+}
@@ -0,0 +1,174 @@
+// Package predicate filters Kubernetes objects with CEL expressions, the
+// language Kubernetes uses for validation rules and admission policies.
+package predicate
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	celast "github.com/google/cel-go/common/ast"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/ext"
+)
+
+// ObjectVariable is the name of the variable holding the evaluated object.
+const ObjectVariable = "object"
+
+// FieldsVariable is the name of the variable holding all the fields of a
+// record evaluated by a predicate created with NewFields.
+const FieldsVariable = "fields"
+
+// typeNames are the identifiers CEL reserves for types, e.g. in type(x) == int.
+var typeNames = []string{"bool", "bytes", "double", "dyn", "int", "list", "map", "null_type", "string", "type", "uint"}
+
+// Predicate is a compiled CEL expression evaluating to a boolean.
+type Predicate struct {
+	expression string
+	program    cel.Program
+}
+
+// New compiles a CEL expression evaluated against an object, available as
+// the "object" variable. For example:
+//
+//	object.spec.replicas > 3
+//	object.spec.template.spec.containers.exists(c, !c.image.startsWith("registry.example.com/"))
+func New(expression string) (*Predicate, error) {
+	env, err := cel.NewEnv(
+		cel.Variable(ObjectVariable, cel.MapType(cel.StringType, cel.DynType)),
+		cel.CrossTypeNumericComparisons(true),
+		ext.Strings(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error creating CEL environment: %v", err)
+	}
+
+	return compile(env, expression)
+}
+
+// NewFields compiles a CEL expression evaluated against the fields of a flat
+// record, such as a structured log line. Each field is available as a
+// variable named after it, and all of them as the "fields" map, which also
+// reaches fields whose names aren't identifiers. For example:
+//
+//	level == "error" && latency_ms > 500
+//	fields["http.status"] >= 500
+func NewFields(expression string) (*Predicate, error) {
+	if strings.TrimSpace(expression) == "" {
+		return nil, fmt.Errorf("invalid expression: empty expression")
+	}
+
+	options := []cel.EnvOption{
+		cel.Variable(FieldsVariable, cel.MapType(cel.StringType, cel.DynType)),
+		cel.CrossTypeNumericComparisons(true),
+		ext.Strings(),
+	}
+
+	// Fields aren't known in advance, so the identifiers the expression
+	// refers to are declared as variables of any type.
+	parser, err := cel.NewEnv(options...)
+	if err != nil {
+		return nil, fmt.Errorf("error creating CEL environment: %v", err)
+	}
+	ast, issues := parser.Parse(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("invalid expression %q: %v", expression, issues.Err())
+	}
+	for _, name := range identifiers(ast) {
+		if name != FieldsVariable {
+			options = append(options, cel.Variable(name, cel.DynType))
+		}
+	}
+
+	env, err := cel.NewEnv(options...)
+	if err != nil {
+		return nil, fmt.Errorf("error creating CEL environment: %v", err)
+	}
+
+	return compile(env, expression)
+}
+
+// identifiers returns the names of the variables an expression refers to,
+// excluding type names and the variables of comprehensions like exists().
+func identifiers(ast *cel.Ast) []string {
+	var names []string
+	local := map[string]bool{}
+	celast.PreOrderVisit(ast.NativeRep().Expr(), celast.NewExprVisitor(func(e celast.Expr) {
+		switch e.Kind() {
+		case celast.ComprehensionKind:
+			comprehension := e.AsComprehension()
+			local[comprehension.IterVar()] = true
+			local[comprehension.IterVar2()] = true
+			local[comprehension.AccuVar()] = true
+		case celast.IdentKind:
+			names = append(names, e.AsIdent())
+		}
+	}))
+
+	names = slices.DeleteFunc(names, func(name string) bool {
+		return local[name] || slices.Contains(typeNames, name)
+	})
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+func compile(env *cel.Env, expression string) (*Predicate, error) {
+	if strings.TrimSpace(expression) == "" {
+		return nil, fmt.Errorf("invalid expression: empty expression")
+	}
+
+	ast, issues := env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("invalid expression %q: %v", expression, issues.Err())
+	}
+
+	if outputType := ast.OutputType(); !outputType.IsAssignableType(cel.BoolType) {
+		return nil, fmt.Errorf("invalid expression %q: must evaluate to a bool, got %s", expression, outputType)
+	}
+
+	program, err := env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %v", expression, err)
+	}
+
+	return &Predicate{expression: expression, program: program}, nil
+}
+
+// String returns the expression the predicate was compiled from.
+func (p *Predicate) String() string {
+	return p.expression
+}
+
+// Evaluate evaluates the predicate against an unstructured object. Errors,
+// such as accessing a field the object doesn't have, are returned along with false.
+// Use has() to test for optional fields.
+func (p *Predicate) Evaluate(object map[string]interface{}) (bool, error) {
+	return p.eval(map[string]interface{}{ObjectVariable: object})
+}
+
+// EvaluateFields evaluates a predicate created with NewFields against the
+// fields of a record. Errors, such as referring to a field the record doesn't
+// have, are returned along with false.
+func (p *Predicate) EvaluateFields(fields map[string]interface{}) (bool, error) {
+	variables := make(map[string]interface{}, len(fields)+1)
+	for name, value := range fields {
+		variables[name] = value
+	}
+	variables[FieldsVariable] = fields
+	return p.eval(variables)
+}
+
+func (p *Predicate) eval(variables map[string]interface{}) (bool, error) {
+	result, _, err := p.program.Eval(variables)
+	if err != nil {
+		return false, fmt.Errorf("error evaluating %q: %v", p.expression, err)
+	}
+
+	matched, ok := result.(types.Bool)
+	if !ok {
+		return false, fmt.Errorf("error evaluating %q: expected a bool, got %s", p.expression, result.Type())
+	}
+
+	return bool(matched), nil
+}
@@ -0,0 +1,195 @@
+package predicate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newDeployment(replicas interface{}, images ...string) map[string]interface{} {
+	var containers []interface{}
+	for _, image := range images {
+		containers = append(containers, map[string]interface{}{"image": image})
+	}
+
+	return map[string]interface{}{
+		"kind":     "Deployment",
+		"metadata": map[string]interface{}{"name": "web"},
+		"spec": map[string]interface{}{
+			"replicas": replicas,
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{"containers": containers},
+			},
+		},
+	}
+}
+
+func TestPredicate_Evaluate(t *testing.T) {
+	expression := `object.spec.replicas > 3 && object.spec.template.spec.containers.exists(c, !c.image.startsWith("registry.example.com/"))`
+	predicate, err := New(expression)
+	require.NoError(t, err)
+	assert.Equal(t, expression, predicate.String())
+
+	testCases := []struct {
+		name     string
+		object   map[string]interface{}
+		expected bool
+	}{
+		{
+			name:     "many replicas with external image",
+			object:   newDeployment(int64(5), "registry.example.com/app:1.0", "docker.io/envoy:1.30"),
+			expected: true,
+		},
+		{
+			name:     "many replicas with internal images",
+			object:   newDeployment(int64(5), "registry.example.com/app:1.0"),
+			expected: false,
+		},
+		{
+			name:     "few replicas",
+			object:   newDeployment(int64(2), "docker.io/envoy:1.30"),
+			expected: false,
+		},
+		{
+			name:     "floating point replicas are compared with integers",
+			object:   newDeployment(float64(4), "docker.io/envoy:1.30"),
+			expected: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			matched, err := predicate.Evaluate(tc.object)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, matched)
+		})
+	}
+}
+
+func TestPredicate_EvaluateMissingField(t *testing.T) {
+	predicate, err := New("object.status.readyReplicas < object.spec.replicas")
+	require.NoError(t, err)
+
+	matched, err := predicate.Evaluate(newDeployment(int64(3)))
+	assert.False(t, matched)
+	assert.Error(t, err)
+
+	guarded, err := New("has(object.status) && object.status.readyReplicas < object.spec.replicas")
+	require.NoError(t, err)
+
+	matched, err = guarded.Evaluate(newDeployment(int64(3)))
+	assert.NoError(t, err)
+	assert.False(t, matched)
+}
+
+func TestNew_Errors(t *testing.T) {
+	testCases := []struct {
+		expression string
+		expected   string
+	}{
+		{expression: "", expected: "empty expression"},
+		{expression: "object.spec.replicas >", expected: "invalid expression"},
+		{expression: "self.spec.replicas > 3", expected: "undeclared reference to 'self'"},
+		{expression: "object.metadata.name + 'x'", expected: "must evaluate to a bool"},
+		{expression: "1 + 2", expected: "must evaluate to a bool"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expression, func(t *testing.T) {
+			_, err := New(tc.expression)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.expected)
+		})
+	}
+}
+
+func TestPredicate_EvaluateFields(t *testing.T) {
+	testCases := []struct {
+		expression string
+		fields     map[string]interface{}
+		expected   bool
+	}{
+		{
+			expression: `level == "error" && latency_ms > 500`,
+			fields:     map[string]interface{}{"level": "error", "latency_ms": int64(730)},
+			expected:   true,
+		},
+		{
+			expression: `level == "error" && latency_ms > 500`,
+			fields:     map[string]interface{}{"level": "error", "latency_ms": float64(120.5)},
+			expected:   false,
+		},
+		{
+			expression: `status == 500`,
+			fields:     map[string]interface{}{"status": float64(500)},
+			expected:   true,
+		},
+		{
+			expression: `fields["http.status"] >= 500`,
+			fields:     map[string]interface{}{"http.status": int64(503)},
+			expected:   true,
+		},
+		{
+			expression: `http.method == "POST"`,
+			fields:     map[string]interface{}{"http": map[string]interface{}{"method": "POST"}},
+			expected:   true,
+		},
+		{
+			expression: `tags.exists(t, t == "db")`,
+			fields:     map[string]interface{}{"tags": []interface{}{"cache", "db"}},
+			expected:   true,
+		},
+		{
+			expression: `type(latency_ms) == int && msg.startsWith("slow")`,
+			fields:     map[string]interface{}{"latency_ms": int64(900), "msg": "slow query"},
+			expected:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expression, func(t *testing.T) {
+			predicate, err := NewFields(tc.expression)
+			require.NoError(t, err)
+
+			matched, err := predicate.EvaluateFields(tc.fields)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, matched)
+		})
+	}
+}
+
+func TestPredicate_EvaluateFieldsMissingField(t *testing.T) {
+	predicate, err := NewFields(`level == "error"`)
+	require.NoError(t, err)
+
+	matched, err := predicate.EvaluateFields(map[string]interface{}{"msg": "started"})
+	assert.False(t, matched)
+	assert.Error(t, err)
+
+	guarded, err := NewFields(`has(fields.level) && level == "error"`)
+	require.NoError(t, err)
+
+	matched, err = guarded.EvaluateFields(map[string]interface{}{"msg": "started"})
+	assert.NoError(t, err)
+	assert.False(t, matched)
+}
+
+func TestNewFields_Errors(t *testing.T) {
+	testCases := []struct {
+		expression string
+		expected   string
+	}{
+		{expression: " ", expected: "empty expression"},
+		{expression: "latency_ms >", expected: "invalid expression"},
+		{expression: `msg + "x"`, expected: "must evaluate to a bool"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expression, func(t *testing.T) {
+			_, err := NewFields(tc.expression)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.expected)
+		})
+	}
+}
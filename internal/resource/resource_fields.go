@@ -0,0 +1,190 @@
+package resource
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// field is a line of the flattened representation of a resource that patterns
+// are matched against. Each field holds a key, a value, or both, rendered as
+// "key: value" without indentation, while path locates the field in the object.
+type field struct {
+	path     string
+	segments []pathSegment
+	text     string
+	// keyEnd is the end of the key in text, or 0 when the field has no key.
+	keyEnd int
+	// valueStart is the start of the value in text, or -1 when the field has no value.
+	valueStart int
+}
+
+// flattenObject walks an unstructured object and returns its fields in the
+// order a YAML rendering would show them: map keys sorted, list items in order.
+func flattenObject(object map[string]interface{}) []field {
+	var fields []field
+	flattenMap(object, location{}, &fields)
+	return fields
+}
+
+// location is the position of a value in an object while flattening it.
+type location struct {
+	path     string
+	segments []pathSegment
+}
+
+func (l location) key(key string) location {
+	return location{path: joinPath(l.path, key), segments: l.append(pathSegment{key: key, index: -1})}
+}
+
+func (l location) index(index int) location {
+	return location{path: fmt.Sprintf("%s[%d]", l.path, index), segments: l.append(pathSegment{index: index})}
+}
+
+// append copies the segments so that siblings don't share them.
+func (l location) append(segment pathSegment) []pathSegment {
+	segments := make([]pathSegment, len(l.segments), len(l.segments)+1)
+	copy(segments, l.segments)
+	return append(segments, segment)
+}
+
+func flattenMap(object map[string]interface{}, at location, fields *[]field) {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		flattenValue(key, object[key], at.key(key), fields)
+	}
+}
+
+func flattenList(list []interface{}, at location, fields *[]field) {
+	for i, item := range list {
+		flattenValue("", item, at.index(i), fields)
+	}
+}
+
+// flattenValue adds the fields of a value. List items have no key.
+func flattenValue(key string, value interface{}, at location, fields *[]field) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 {
+			*fields = append(*fields, newField(at, key, "{}"))
+			return
+		}
+		if key != "" {
+			*fields = append(*fields, newField(at, key, ""))
+		}
+		flattenMap(v, at, fields)
+	case []interface{}:
+		if len(v) == 0 {
+			*fields = append(*fields, newField(at, key, "[]"))
+			return
+		}
+		if key != "" {
+			*fields = append(*fields, newField(at, key, ""))
+		}
+		flattenList(v, at, fields)
+	case string:
+		if !strings.Contains(v, "\n") {
+			*fields = append(*fields, newField(at, key, v))
+			return
+		}
+		// Multi-line strings, such as files stored in ConfigMaps, are matched
+		// line by line like YAML block scalars.
+		*fields = append(*fields, newField(at, key, "|"))
+		for _, line := range strings.Split(strings.TrimSuffix(v, "\n"), "\n") {
+			*fields = append(*fields, field{path: at.path, segments: at.segments, text: line, valueStart: 0})
+		}
+	default:
+		*fields = append(*fields, newField(at, key, scalarString(v)))
+	}
+}
+
+func newField(at location, key, value string) field {
+	f := field{path: at.path, segments: at.segments, valueStart: -1}
+	switch {
+	case key == "":
+		f.text = value
+		f.valueStart = 0
+	case value == "":
+		f.text = key + ":"
+		f.keyEnd = len(key)
+	default:
+		f.text = key + ": " + value
+		f.keyEnd = len(key)
+		f.valueStart = len(key) + 2
+	}
+	return f
+}
+
+func scalarString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// joinPath appends a key to a field path. Keys that aren't plain identifiers,
+// like annotation names, are written in brackets.
+func joinPath(path, key string) string {
+	if !isPlainKey(key) {
+		return fmt.Sprintf("%s[%q]", path, key)
+	}
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func isPlainKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, c := range key {
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '_' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// inKey reports whether a span of the field's text falls within its key.
+func (f field) inKey(start int) bool {
+	return start < f.keyEnd
+}
+
+// inValue reports whether a span of the field's text falls within its value.
+func (f field) inValue(end int) bool {
+	return f.valueStart >= 0 && end > f.valueStart
+}
+
+// matchText returns the part of the field's text that patterns are matched
+// against for the given target, and its offset in the text.
+func (f field) matchText(target Target) (string, int, bool) {
+	switch target {
+	case TargetKeys:
+		if f.keyEnd == 0 {
+			return "", 0, false
+		}
+		return f.text[:f.keyEnd], 0, true
+	case TargetValues:
+		if f.valueStart < 0 {
+			return "", 0, false
+		}
+		return f.text[f.valueStart:], f.valueStart, true
+	default:
+		return f.text, 0, true
+	}
+}
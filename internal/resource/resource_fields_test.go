@@ -0,0 +1,74 @@
+package resource
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlattenObject(t *testing.T) {
+	fields := flattenObject(map[string]interface{}{
+		"kind": "ConfigMap",
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{
+				"example.com/owner": "team-a",
+			},
+			"labels": map[string]interface{}{},
+		},
+		"data": map[string]interface{}{
+			"app.properties": "db.url=postgres://db\nretries=3\n",
+		},
+		"spec": map[string]interface{}{
+			"replicas": int64(3),
+			"paused":   false,
+			"ports":    []interface{}{int64(80), int64(443)},
+			"ratio":    0.5,
+			"extra":    nil,
+		},
+	})
+
+	type line struct {
+		path string
+		text string
+	}
+	var lines []line
+	for _, f := range fields {
+		lines = append(lines, line{f.path, f.text})
+	}
+
+	assert.Equal(t, []line{
+		{"data", "data:"},
+		{`data["app.properties"]`, "app.properties: |"},
+		{`data["app.properties"]`, "db.url=postgres://db"},
+		{`data["app.properties"]`, "retries=3"},
+		{"kind", "kind: ConfigMap"},
+		{"metadata", "metadata:"},
+		{"metadata.annotations", "annotations:"},
+		{`metadata.annotations["example.com/owner"]`, "example.com/owner: team-a"},
+		{"metadata.labels", "labels: {}"},
+		{"spec", "spec:"},
+		{"spec.extra", "extra: null"},
+		{"spec.paused", "paused: false"},
+		{"spec.ports", "ports:"},
+		{"spec.ports[0]", "80"},
+		{"spec.ports[1]", "443"},
+		{"spec.ratio", "ratio: 0.5"},
+		{"spec.replicas", "replicas: 3"},
+	}, lines)
+}
+
+func TestField_KeyAndValue(t *testing.T) {
+	f := newField(location{path: "spec.image"}, "image", "nginx")
+	assert.True(t, f.inKey(0))
+	assert.False(t, f.inKey(7))
+	assert.False(t, f.inValue(5))
+	assert.True(t, f.inValue(9))
+
+	item := newField(location{path: "spec.ports[0]"}, "", "80")
+	assert.False(t, item.inKey(0))
+	assert.True(t, item.inValue(2))
+
+	parent := newField(location{path: "spec"}, "spec", "")
+	assert.True(t, parent.inKey(0))
+	assert.False(t, parent.inValue(4))
+}
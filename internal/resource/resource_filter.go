@@ -0,0 +1,129 @@
+package resource
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+)
+
+// AnnotationRequirement is a condition on the annotations of a resource.
+// The API server can't filter resources by annotation, so requirements are
+// evaluated client-side.
+type AnnotationRequirement struct {
+	Key string
+	// Value is compared when HasValue is set. Otherwise only the presence of
+	// the key is checked.
+	Value    string
+	HasValue bool
+	// Negated inverts the requirement.
+	Negated bool
+}
+
+// ParseAnnotationRequirement parses a requirement of the form key, !key,
+// key=value or key!=value. Unlike label selectors, values may contain any character.
+func ParseAnnotationRequirement(requirement string) (AnnotationRequirement, error) {
+	var r AnnotationRequirement
+
+	switch {
+	case strings.HasPrefix(requirement, "!"):
+		r.Key = requirement[1:]
+		r.Negated = true
+	case strings.Contains(requirement, "!="):
+		r.Key, r.Value, _ = strings.Cut(requirement, "!=")
+		r.HasValue = true
+		r.Negated = true
+	case strings.Contains(requirement, "="):
+		r.Key, r.Value, _ = strings.Cut(requirement, "=")
+		r.Value = strings.TrimPrefix(r.Value, "=")
+		r.HasValue = true
+	default:
+		r.Key = requirement
+	}
+
+	r.Key = strings.TrimSpace(r.Key)
+	if r.Key == "" {
+		return AnnotationRequirement{}, fmt.Errorf("invalid annotation requirement %q: empty key", requirement)
+	}
+
+	return r, nil
+}
+
+// Matches reports whether annotations satisfy the requirement.
+func (r AnnotationRequirement) Matches(annotations map[string]string) bool {
+	value, found := annotations[r.Key]
+	matched := found
+	if r.HasValue {
+		matched = found && value == r.Value
+	}
+	return matched != r.Negated
+}
+
+// String returns the requirement in the form it's parsed from.
+func (r AnnotationRequirement) String() string {
+	s := r.Key
+	if r.HasValue {
+		if r.Negated {
+			return s + "!=" + r.Value
+		}
+		return s + "=" + r.Value
+	}
+	if r.Negated {
+		return "!" + s
+	}
+	return s
+}
+
+// matchesAnnotations reports whether an object satisfies all annotation requirements.
+func matchesAnnotations(object *unstructured.Unstructured, requirements []AnnotationRequirement) bool {
+	annotations := object.GetAnnotations()
+	for _, requirement := range requirements {
+		if !requirement.Matches(annotations) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesFieldSelector evaluates a field selector against an object, for
+// resources whose API doesn't support the selected fields. Fields are given as
+// dot-separated paths, e.g. spec.nodeName, and missing fields are empty.
+func matchesFieldSelector(object *unstructured.Unstructured, selector fields.Selector) bool {
+	set := fields.Set{}
+	for _, requirement := range selector.Requirements() {
+		value, found, err := unstructured.NestedFieldNoCopy(object.Object, strings.Split(requirement.Field, ".")...)
+		if err != nil || !found || value == nil {
+			set[requirement.Field] = ""
+			continue
+		}
+		set[requirement.Field] = fmt.Sprint(value)
+	}
+	return selector.Matches(set)
+}
+
+// isGlob reports whether a name is a glob pattern rather than an exact name.
+func isGlob(name string) bool {
+	return strings.ContainsAny(name, "*?[")
+}
+
+// ValidateName checks that a resource name or glob pattern is well-formed.
+func ValidateName(name string) error {
+	if name == "" {
+		return fmt.Errorf("resource name cannot be empty")
+	}
+	if _, err := path.Match(name, ""); err != nil {
+		return fmt.Errorf("invalid name pattern %q: %v", name, err)
+	}
+	return nil
+}
+
+func matchesAnyGlob(name string, globs []string) bool {
+	for _, glob := range globs {
+		if matched, _ := path.Match(glob, name); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,289 @@
+package resource
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// FakeLister is a test implementation of the Lister interface.
+type FakeLister struct {
+	// items holds the resources returned for every kind and namespace.
+	items []unstructured.Unstructured
+	// supportedFields are the fields the fake API server can select on.
+	supportedFields map[string]bool
+	// calls records the options of every List call.
+	calls []metav1.ListOptions
+	// gets records the names of every Get call.
+	gets []string
+	// expired holds continue tokens rejected once as expired.
+	expired map[string]bool
+}
+
+// List returns the stored resources, rejecting unsupported field selectors like the API server does.
+func (f *FakeLister) List(_ context.Context, _, _ string, options metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	f.calls = append(f.calls, options)
+
+	if options.FieldSelector != "" {
+		selector, err := fields.ParseSelector(options.FieldSelector)
+		if err != nil {
+			return nil, err
+		}
+		for _, requirement := range selector.Requirements() {
+			if !f.supportedFields[requirement.Field] {
+				return nil, apierrors.NewBadRequest("field label not supported: " + requirement.Field)
+			}
+		}
+	}
+
+	if f.expired[options.Continue] {
+		delete(f.expired, options.Continue)
+		return nil, apierrors.NewResourceExpired("the provided continue parameter is too old")
+	}
+
+	// Pages continue from the index of their first item.
+	start, end := 0, len(f.items)
+	if options.Continue != "" {
+		start, _ = strconv.Atoi(options.Continue)
+	}
+	if options.Limit > 0 {
+		end = min(start+int(options.Limit), len(f.items))
+	}
+
+	list := &unstructured.UnstructuredList{}
+	for i := start; i < end; i++ {
+		list.Items = append(list.Items, *f.items[i].DeepCopy())
+	}
+	if end < len(f.items) {
+		list.SetContinue(strconv.Itoa(end))
+	}
+	return list, nil
+}
+
+// Get returns the stored resource with the given name.
+func (f *FakeLister) Get(_ context.Context, resource, _, name string) (*unstructured.Unstructured, error) {
+	f.gets = append(f.gets, name)
+
+	for _, item := range f.items {
+		if item.GetName() == name {
+			return item.DeepCopy(), nil
+		}
+	}
+	return nil, apierrors.NewNotFound(schema.GroupResource{Resource: resource}, name)
+}
+
+func newPod(name, node string, annotations map[string]interface{}) unstructured.Unstructured {
+	return unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{"name": name, "annotations": annotations},
+		"spec":     map[string]interface{}{"nodeName": node},
+	}}
+}
+
+func names(objects []unstructured.Unstructured) []string {
+	var names []string
+	for _, item := range objects {
+		names = append(names, item.GetName())
+	}
+	return names
+}
+
+// listAll lists pods with the searcher and returns them along with the
+// number of chunks they were listed in.
+func listAll(searcher *Searcher) ([]unstructured.Unstructured, int, error) {
+	var resources []unstructured.Unstructured
+	pages := 0
+	err := searcher.list("pods", "default", func(items []unstructured.Unstructured) error {
+		resources = append(resources, items...)
+		pages++
+		return nil
+	})
+	return resources, pages, err
+}
+
+func TestSearcher_List_PushesDownSelectors(t *testing.T) {
+	lister := &FakeLister{
+		items:           []unstructured.Unstructured{newPod("web", "node-1", nil)},
+		supportedFields: map[string]bool{"metadata.name": true},
+	}
+	searcher := &Searcher{lister: lister, options: Options{LabelSelector: "app=checkout", FieldSelector: "metadata.name=web"}}
+
+	resources, pages, err := listAll(searcher)
+	require.NoError(t, err)
+	assert.Equal(t, 1, pages)
+	assert.Equal(t, []string{"web"}, names(resources))
+	assert.Equal(t, []metav1.ListOptions{{LabelSelector: "app=checkout", FieldSelector: "metadata.name=web"}}, lister.calls)
+}
+
+func TestSearcher_List_UnsupportedFieldSelectorIsAppliedClientSide(t *testing.T) {
+	lister := &FakeLister{
+		items: []unstructured.Unstructured{
+			newPod("web-1", "node-1", nil),
+			newPod("web-2", "node-2", nil),
+		},
+	}
+	searcher := &Searcher{lister: lister, options: Options{LabelSelector: "app=web", FieldSelector: "spec.nodeName=node-2"}}
+
+	resources, pages, err := listAll(searcher)
+	require.NoError(t, err)
+	assert.Equal(t, 1, pages)
+	assert.Equal(t, []string{"web-2"}, names(resources))
+	assert.Equal(t, []metav1.ListOptions{
+		{LabelSelector: "app=web", FieldSelector: "spec.nodeName=node-2"},
+		{LabelSelector: "app=web"},
+	}, lister.calls)
+}
+
+func TestSearcher_List_Annotations(t *testing.T) {
+	lister := &FakeLister{
+		items: []unstructured.Unstructured{
+			newPod("owned", "node-1", map[string]interface{}{"example.com/owner": "team a"}),
+			newPod("other", "node-1", map[string]interface{}{"example.com/owner": "team b"}),
+			newPod("orphan", "node-1", nil),
+		},
+	}
+
+	requirement, err := ParseAnnotationRequirement("example.com/owner=team a")
+	require.NoError(t, err)
+	searcher := &Searcher{lister: lister, options: Options{Annotations: []AnnotationRequirement{requirement}}}
+
+	resources, pages, err := listAll(searcher)
+	require.NoError(t, err)
+	assert.Equal(t, 1, pages)
+	assert.Equal(t, []string{"owned"}, names(resources))
+}
+
+func newPods(count int) []unstructured.Unstructured {
+	var pods []unstructured.Unstructured
+	for i := 0; i < count; i++ {
+		pods = append(pods, newPod(fmt.Sprintf("web-%d", i), "node-1", nil))
+	}
+	return pods
+}
+
+func TestSearcher_List_Chunks(t *testing.T) {
+	lister := &FakeLister{items: newPods(5)}
+	searcher := &Searcher{lister: lister, options: Options{LabelSelector: "app=web", ChunkSize: 2}}
+
+	resources, pages, err := listAll(searcher)
+	require.NoError(t, err)
+	assert.Equal(t, 3, pages)
+	assert.Equal(t, []string{"web-0", "web-1", "web-2", "web-3", "web-4"}, names(resources))
+	assert.Equal(t, []metav1.ListOptions{
+		{LabelSelector: "app=web", Limit: 2},
+		{LabelSelector: "app=web", Limit: 2, Continue: "2"},
+		{LabelSelector: "app=web", Limit: 2, Continue: "4"},
+	}, lister.calls)
+}
+
+func TestSearcher_List_ChunksWithUnsupportedFieldSelector(t *testing.T) {
+	lister := &FakeLister{items: newPods(4)}
+	searcher := &Searcher{lister: lister, options: Options{FieldSelector: "metadata.name!=web-2", ChunkSize: 3}}
+
+	resources, _, err := listAll(searcher)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"web-0", "web-1", "web-3"}, names(resources))
+	assert.Equal(t, []metav1.ListOptions{
+		{FieldSelector: "metadata.name!=web-2", Limit: 3},
+		{Limit: 3},
+		{Limit: 3, Continue: "3"},
+	}, lister.calls, "the field selector is only tried once")
+}
+
+func TestSearcher_List_RestartsWhenContinueTokenExpires(t *testing.T) {
+	lister := &FakeLister{items: newPods(5), expired: map[string]bool{"4": true}}
+	searcher := &Searcher{lister: lister, options: Options{ChunkSize: 2}}
+
+	resources, _, err := listAll(searcher)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"web-0", "web-1", "web-2", "web-3", "web-4"}, names(resources), "resources are listed once")
+}
+
+func TestSearcher_ListGenericResources_NoFallbackAfterFirstChunk(t *testing.T) {
+	lister := &FakeLister{items: newPods(3)}
+	searcher := &Searcher{kind: "pods", lister: lister, options: Options{ChunkSize: 2}}
+
+	err := searcher.listGenericResources("default", func([]unstructured.Unstructured) error {
+		return fmt.Errorf("stop")
+	})
+	require.Error(t, err)
+	assert.Len(t, lister.calls, 1)
+}
+
+func TestParseAnnotationRequirement(t *testing.T) {
+	annotations := map[string]string{"example.com/owner": "team-a", "example.com/url": "https://a.example.com/?x=1"}
+
+	testCases := []struct {
+		requirement string
+		expected    AnnotationRequirement
+		matches     bool
+	}{
+		{requirement: "example.com/owner", expected: AnnotationRequirement{Key: "example.com/owner"}, matches: true},
+		{requirement: "!example.com/owner", expected: AnnotationRequirement{Key: "example.com/owner", Negated: true}, matches: false},
+		{requirement: "example.com/owner=team-a", expected: AnnotationRequirement{Key: "example.com/owner", Value: "team-a", HasValue: true}, matches: true},
+		{requirement: "example.com/owner==team-b", expected: AnnotationRequirement{Key: "example.com/owner", Value: "team-b", HasValue: true}, matches: false},
+		{requirement: "example.com/owner!=team-b", expected: AnnotationRequirement{Key: "example.com/owner", Value: "team-b", HasValue: true, Negated: true}, matches: true},
+		{requirement: "example.com/url=https://a.example.com/?x=1", expected: AnnotationRequirement{Key: "example.com/url", Value: "https://a.example.com/?x=1", HasValue: true}, matches: true},
+		{requirement: "example.com/missing!=x", expected: AnnotationRequirement{Key: "example.com/missing", Value: "x", HasValue: true, Negated: true}, matches: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.requirement, func(t *testing.T) {
+			requirement, err := ParseAnnotationRequirement(tc.requirement)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, requirement)
+			assert.Equal(t, tc.matches, requirement.Matches(annotations))
+		})
+	}
+
+	_, err := ParseAnnotationRequirement("=value")
+	assert.Error(t, err)
+}
+
+func TestSearcher_GetNamedResources(t *testing.T) {
+	lister := &FakeLister{
+		items: []unstructured.Unstructured{
+			newPod("web-1", "node-1", nil),
+			newPod("web-2", "node-2", nil),
+			newPod("db", "node-1", nil),
+		},
+	}
+	searcher := &Searcher{kind: "pods", lister: lister, options: Options{Names: []string{"db", "web-*"}}}
+
+	objects, err := searcher.getNamedResources("default", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"db", "web-1", "web-2"}, names(objects))
+	assert.Equal(t, []string{"db"}, lister.gets, "exact names are fetched directly")
+}
+
+func TestSearcher_GetNamedResources_NotFound(t *testing.T) {
+	searcher := &Searcher{kind: "configmaps", lister: &FakeLister{}, options: Options{Names: []string{"missing"}}}
+
+	_, err := searcher.getNamedResources("default", nil)
+	require.Error(t, err)
+	assert.Equal(t, `configmaps "missing" not found in namespace "default"`, err.Error())
+}
+
+func TestSearcher_GetNamedResources_SelectorsAppliedToDirectGet(t *testing.T) {
+	lister := &FakeLister{items: []unstructured.Unstructured{newPod("web-1", "node-1", nil)}}
+	searcher := &Searcher{kind: "pods", lister: lister, options: Options{Names: []string{"web-1"}, FieldSelector: "spec.nodeName=node-2"}}
+
+	objects, err := searcher.getNamedResources("default", nil)
+	require.NoError(t, err)
+	assert.Empty(t, objects)
+}
+
+func TestValidateName(t *testing.T) {
+	assert.NoError(t, ValidateName("web-*"))
+	assert.NoError(t, ValidateName("my-config"))
+	assert.Error(t, ValidateName(""))
+	assert.Error(t, ValidateName("web-[a"))
+}
@@ -0,0 +1,192 @@
+package resource
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+)
+
+// Lister lists Kubernetes resources of a kind, like kubectl get does.
+// This allows for swapping a fake implementation during testing.
+type Lister interface {
+	// List lists the resources of a kind, given as a kind, plural or short
+	// name, in a namespace, or cluster-wide if namespace is empty.
+	List(ctx context.Context, resource, namespace string, options metav1.ListOptions) (*unstructured.UnstructuredList, error)
+	// Get gets a resource of a kind by name.
+	Get(ctx context.Context, resource, namespace, name string) (*unstructured.Unstructured, error)
+}
+
+// DefaultLister is the production implementation of Lister.
+// It resolves resource names through the discovery API and lists resources
+// with the dynamic client.
+//
+// Resource name resolution is forked from github.com/hbelmiro/go-kube-get,
+// which this package used to depend on. Its KubeGet.Get always lists a whole
+// kind without list options, so it can't push label and field selectors or
+// chunk sizes down to the API server, nor get a single object by name.
+type DefaultLister struct {
+	restMapper      meta.RESTMapper
+	dynamicClient   dynamic.Interface
+	discoveryClient discovery.CachedDiscoveryInterface
+}
+
+// NewDefaultLister creates a DefaultLister using the provided Kubernetes configuration.
+func NewDefaultLister(config *rest.Config) (*DefaultLister, error) {
+	if config == nil {
+		return nil, fmt.Errorf("config cannot be nil")
+	}
+
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("error creating discovery client: %v", err)
+	}
+
+	// Cache discovery so that resource names are resolved without repeated requests.
+	cachedClient := memory.NewMemCacheClient(discoveryClient)
+
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("error creating dynamic client: %v", err)
+	}
+
+	return &DefaultLister{
+		restMapper:      restmapper.NewDeferredDiscoveryRESTMapper(cachedClient),
+		dynamicClient:   dynamicClient,
+		discoveryClient: cachedClient,
+	}, nil
+}
+
+// List lists the resources of a kind in a namespace.
+func (l *DefaultLister) List(ctx context.Context, resource, namespace string, options metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	resourceInterface, err := l.resourceInterface(resource, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := resourceInterface.List(ctx, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list resources: %w", err)
+	}
+
+	return list, nil
+}
+
+// Get gets a resource of a kind by name.
+func (l *DefaultLister) Get(ctx context.Context, resource, namespace, name string) (*unstructured.Unstructured, error) {
+	resourceInterface, err := l.resourceInterface(resource, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	object, err := resourceInterface.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get resource: %w", err)
+	}
+
+	return object, nil
+}
+
+// resourceInterface resolves a resource name to a dynamic client interface.
+// The namespace is ignored for cluster-scoped resources.
+func (l *DefaultLister) resourceInterface(resource, namespace string) (dynamic.ResourceInterface, error) {
+	gvr, err := l.findGVR(resource)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find resource %q: %v", resource, err)
+	}
+
+	if namespace == "" || !l.isNamespaced(gvr) {
+		return l.dynamicClient.Resource(gvr), nil
+	}
+	return l.dynamicClient.Resource(gvr).Namespace(namespace), nil
+}
+
+// isNamespaced reports whether a resource is namespaced. Resources whose
+// scope can't be determined are assumed to be namespaced.
+func (l *DefaultLister) isNamespaced(gvr schema.GroupVersionResource) bool {
+	gvk, err := l.restMapper.KindFor(gvr)
+	if err != nil {
+		return true
+	}
+
+	mapping, err := l.restMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return true
+	}
+
+	return mapping.Scope.Name() != meta.RESTScopeNameRoot
+}
+
+// findGVR resolves a kind, plural or short name to its GroupVersionResource.
+func (l *DefaultLister) findGVR(resource string) (schema.GroupVersionResource, error) {
+	if resource == "" {
+		return schema.GroupVersionResource{}, fmt.Errorf("resource name cannot be empty")
+	}
+
+	// Fully qualified names have the form resource.version.group, where the
+	// group may contain dots.
+	if parts := strings.Split(resource, "."); len(parts) >= 3 {
+		return schema.GroupVersionResource{
+			Group:    strings.Join(parts[2:], "."),
+			Version:  parts[1],
+			Resource: parts[0],
+		}, nil
+	}
+
+	if gvr, err := l.restMapper.ResourceFor(schema.GroupVersionResource{Resource: resource}); err == nil {
+		return gvr, nil
+	}
+
+	// Kinds are matched with a few case variations, e.g. "configmap" -> "Configmap" -> "CONFIGMAP".
+	kinds := []string{
+		resource,
+		strings.ToUpper(resource[:1]) + strings.ToLower(resource[1:]),
+		strings.ToUpper(resource),
+	}
+	for _, kind := range kinds {
+		mappings, err := l.restMapper.RESTMappings(schema.GroupKind{Kind: kind})
+		if err == nil && len(mappings) > 0 {
+			return mappings[0].Resource, nil
+		}
+	}
+
+	// Last resort: look for the name among the kinds and short names of all resources.
+	apiResourceLists, err := l.discoveryClient.ServerPreferredResources()
+	if err != nil {
+		return schema.GroupVersionResource{}, err
+	}
+
+	for _, apiResourceList := range apiResourceLists {
+		if apiResourceList == nil {
+			continue
+		}
+
+		gv, err := schema.ParseGroupVersion(apiResourceList.GroupVersion)
+		if err != nil {
+			continue
+		}
+
+		for _, apiResource := range apiResourceList.APIResources {
+			if apiResource.Name == resource || strings.EqualFold(apiResource.Kind, resource) {
+				return gv.WithResource(apiResource.Name), nil
+			}
+
+			for _, shortName := range apiResource.ShortNames {
+				if shortName == resource {
+					return gv.WithResource(apiResource.Name), nil
+				}
+			}
+		}
+	}
+
+	return schema.GroupVersionResource{}, fmt.Errorf("resource not found in any API group")
+}
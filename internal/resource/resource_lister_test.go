@@ -0,0 +1,198 @@
+package resource
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/hbelmiro/kgrep/internal/match"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+var configMapsGVR = schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+
+// newFakeSearcher creates a configmaps Searcher backed by a fake dynamic client
+// holding count ConfigMaps in each namespace.
+func newFakeSearcher(namespaces []string, count int) (*Searcher, *dynamicfake.FakeDynamicClient) {
+	var objects []runtime.Object
+	var namespaceObjects []runtime.Object
+	for _, namespace := range namespaces {
+		namespaceObjects = append(namespaceObjects, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}})
+		for i := 0; i < count; i++ {
+			objects = append(objects, &unstructured.Unstructured{Object: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "ConfigMap",
+				"metadata":   map[string]interface{}{"name": fmt.Sprintf("config-%d", i), "namespace": namespace},
+				"data":       map[string]interface{}{"url": fmt.Sprintf("https://service-%d.example.com", i)},
+			}})
+		}
+	}
+
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{configMapsGVR: "ConfigMapList"}, objects...)
+
+	restMapper := meta.NewDefaultRESTMapper(nil)
+	restMapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, meta.RESTScopeNamespace)
+
+	return &Searcher{
+		kind:          "configmaps",
+		clientset:     fake.NewClientset(namespaceObjects...),
+		dynamicClient: dynamicClient,
+		lister:        &DefaultLister{restMapper: restMapper, dynamicClient: dynamicClient},
+	}, dynamicClient
+}
+
+func countActions(client *dynamicfake.FakeDynamicClient, verb string) int {
+	count := 0
+	for _, action := range client.Actions() {
+		if action.GetVerb() == verb {
+			count++
+		}
+	}
+	return count
+}
+
+func TestSearcher_Search_ListsOncePerNamespace(t *testing.T) {
+	searcher, client := newFakeSearcher([]string{"default"}, 50)
+
+	occurrences, err := searcher.Search("default", newMatcher(t, "example.com"))
+	require.NoError(t, err)
+	assert.Len(t, occurrences, 50)
+	assert.Equal(t, 1, countActions(client, "list"))
+	assert.Equal(t, 0, countActions(client, "get"))
+}
+
+func TestSearcher_SearchAllNamespaces_ListsOncePerNamespace(t *testing.T) {
+	searcher, client := newFakeSearcher([]string{"team-a", "team-b", "team-c"}, 20)
+
+	occurrences, err := searcher.SearchAllNamespaces(newMatcher(t, "service-7."))
+	require.NoError(t, err)
+	assert.Len(t, occurrences, 3)
+	assert.Equal(t, 3, countActions(client, "list"))
+}
+
+func TestSearcher_Search_NamedObjectIsFetchedDirectly(t *testing.T) {
+	searcher, client := newFakeSearcher([]string{"default"}, 50)
+	searcher.SetOptions(Options{Names: []string{"config-7"}})
+
+	occurrences, err := searcher.Search("default", newMatcher(t, "example.com"))
+	require.NoError(t, err)
+	require.Len(t, occurrences, 1)
+	assert.Equal(t, "config-7", occurrences[0].Resource)
+	assert.Equal(t, 0, countActions(client, "list"))
+	assert.Equal(t, 1, countActions(client, "get"))
+}
+
+func BenchmarkSearcher_Search(b *testing.B) {
+	for _, count := range []int{10, 100, 500} {
+		b.Run(fmt.Sprintf("%d-configmaps", count), func(b *testing.B) {
+			searcher, client := newFakeSearcher([]string{"default"}, count)
+			matcher, err := match.New("service-1", match.Options{})
+			require.NoError(b, err)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := searcher.Search("default", matcher); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.StopTimer()
+
+			// The number of list calls must not grow with the number of objects.
+			lists := countActions(client, "list")
+			b.ReportMetric(float64(lists)/float64(b.N), "lists/op")
+			if lists != b.N {
+				b.Fatalf("expected 1 list call per search, got %d for %d searches", lists, b.N)
+			}
+		})
+	}
+}
+
+func TestSearcher_SearchAllNamespaces_ConcurrentAndOrdered(t *testing.T) {
+	searcher, client := newFakeSearcher([]string{"team-a", "team-b", "team-c", "team-d"}, 10)
+	client.PrependReactor("list", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		// The cluster-wide fallback list fails as well, as it would without cluster-wide access.
+		if action.GetNamespace() == "team-c" || action.GetNamespace() == "" {
+			return true, nil, apierrors.NewForbidden(configMapsGVR.GroupResource(), "", fmt.Errorf("access denied"))
+		}
+		return false, nil, nil
+	})
+
+	var errs []error
+	searcher.SetOptions(Options{Concurrency: 4, OnError: func(err error) { errs = append(errs, err) }})
+
+	occurrences, err := searcher.SearchAllNamespaces(newMatcher(t, "example.com"))
+	require.NoError(t, err)
+
+	var namespaces []string
+	for _, occurrence := range occurrences {
+		if len(namespaces) == 0 || namespaces[len(namespaces)-1] != occurrence.Namespace {
+			namespaces = append(namespaces, occurrence.Namespace)
+		}
+	}
+	assert.Equal(t, []string{"team-a", "team-b", "team-d"}, namespaces)
+	assert.Equal(t, "config-0", occurrences[0].Resource)
+	assert.Equal(t, "config-9", occurrences[9].Resource)
+
+	require.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Error(), "namespace team-c")
+}
+
+func TestSearcher_SearchAllNamespacesStream(t *testing.T) {
+	searcher, _ := newFakeSearcher([]string{"team-a", "team-b", "team-c"}, 10)
+	searcher.SetOptions(Options{Concurrency: 4, ChunkSize: 3})
+
+	var streamed []Occurrence
+	err := searcher.SearchAllNamespacesStream(newMatcher(t, "example.com"), func(occurrence Occurrence) {
+		streamed = append(streamed, occurrence)
+	})
+	require.NoError(t, err)
+
+	collected, err := searcher.SearchAllNamespaces(newMatcher(t, "example.com"))
+	require.NoError(t, err)
+	assert.Len(t, streamed, 30)
+	assert.Equal(t, collected, streamed, "streamed occurrences are in the same order as collected ones")
+}
+
+func TestSearcher_SearchAllNamespaces_NamedObjectInSomeNamespaces(t *testing.T) {
+	searcher, _ := newFakeSearcher([]string{"team-a", "team-b", "team-c"}, 1)
+	_, err := searcher.dynamicClient.Resource(configMapsGVR).Namespace("team-b").Create(context.Background(), &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   map[string]interface{}{"name": "my-config", "namespace": "team-b"},
+		"data":       map[string]interface{}{"url": "https://my-service.example.com"},
+	}}, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	var errs []error
+	searcher.SetOptions(Options{Names: []string{"my-config"}, OnError: func(err error) { errs = append(errs, err) }})
+
+	occurrences, err := searcher.SearchAllNamespaces(newMatcher(t, "example.com"))
+	require.NoError(t, err)
+	require.Len(t, occurrences, 1)
+	assert.Equal(t, "team-b", occurrences[0].Namespace)
+	assert.Empty(t, errs, "namespaces without the object aren't reported")
+}
+
+func TestSearcher_SearchAllNamespaces_NamedObjectNotFound(t *testing.T) {
+	searcher, _ := newFakeSearcher([]string{"team-a", "team-b"}, 1)
+
+	var errs []error
+	searcher.SetOptions(Options{Names: []string{"my-config"}, OnError: func(err error) { errs = append(errs, err) }})
+
+	_, err := searcher.SearchAllNamespaces(newMatcher(t, "example.com"))
+	require.Error(t, err)
+	assert.Equal(t, `configmaps "my-config" not found`, err.Error())
+	assert.Empty(t, errs)
+}
@@ -0,0 +1,41 @@
+package resource
+
+// lastAppliedConfigurationAnnotation holds a copy of the whole object as last
+// applied by kubectl, duplicating every match.
+const lastAppliedConfigurationAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// NoiseFilter configures which noisy parts of resources are pruned before
+// matching. The zero value prunes managed fields, the last applied
+// configuration, resourceVersion, uid and status.
+type NoiseFilter struct {
+	IncludeManagedFields bool
+	IncludeStatus        bool
+	// Raw disables pruning altogether.
+	Raw bool
+}
+
+// prune removes the noisy parts of an unstructured object in place.
+func (f NoiseFilter) prune(object map[string]interface{}) {
+	if f.Raw {
+		return
+	}
+
+	if metadata, ok := object["metadata"].(map[string]interface{}); ok {
+		if !f.IncludeManagedFields {
+			delete(metadata, "managedFields")
+		}
+		delete(metadata, "resourceVersion")
+		delete(metadata, "uid")
+
+		if annotations, ok := metadata["annotations"].(map[string]interface{}); ok {
+			delete(annotations, lastAppliedConfigurationAnnotation)
+			if len(annotations) == 0 {
+				delete(metadata, "annotations")
+			}
+		}
+	}
+
+	if !f.IncludeStatus {
+		delete(object, "status")
+	}
+}
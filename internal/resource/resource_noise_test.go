@@ -0,0 +1,69 @@
+package resource
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newNoisyObject() map[string]interface{} {
+	return map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"name":            "web",
+			"uid":             "0b6f1c1e-2a4b-4c5d-9e8f-123456789abc",
+			"resourceVersion": "12345",
+			"managedFields": []interface{}{
+				map[string]interface{}{"manager": "kubectl-client-side-apply"},
+			},
+			"annotations": map[string]interface{}{
+				"kubectl.kubernetes.io/last-applied-configuration": `{"metadata":{"name":"web"}}`,
+				"example.com/owner": "team-a",
+			},
+		},
+		"spec":   map[string]interface{}{"replicas": int64(2)},
+		"status": map[string]interface{}{"readyReplicas": int64(2)},
+	}
+}
+
+func TestNoiseFilter_Prune(t *testing.T) {
+	object := newNoisyObject()
+	NoiseFilter{}.prune(object)
+
+	assert.Equal(t, map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"name": "web",
+			"annotations": map[string]interface{}{
+				"example.com/owner": "team-a",
+			},
+		},
+		"spec": map[string]interface{}{"replicas": int64(2)},
+	}, object)
+}
+
+func TestNoiseFilter_Overrides(t *testing.T) {
+	object := newNoisyObject()
+	NoiseFilter{IncludeManagedFields: true, IncludeStatus: true}.prune(object)
+
+	metadata := object["metadata"].(map[string]interface{})
+	assert.Contains(t, metadata, "managedFields")
+	assert.Contains(t, object, "status")
+	assert.NotContains(t, metadata, "uid")
+	assert.NotContains(t, metadata["annotations"], lastAppliedConfigurationAnnotation)
+
+	object = newNoisyObject()
+	NoiseFilter{Raw: true}.prune(object)
+	assert.Equal(t, newNoisyObject(), object)
+}
+
+func TestNoiseFilter_RemovesEmptyAnnotations(t *testing.T) {
+	object := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{
+				lastAppliedConfigurationAnnotation: "{}",
+			},
+		},
+	}
+	NoiseFilter{}.prune(object)
+
+	assert.Equal(t, map[string]interface{}{"metadata": map[string]interface{}{}}, object)
+}
@@ -1,9 +1,33 @@
 package resource
 
+import "github.com/hbelmiro/kgrep/internal/match"
+
 // Occurrence represents an occurrence of a pattern in a Kubernetes resource.
 type Occurrence struct {
 	Resource  string
 	Namespace string
-	Line      int
-	Content   string
+	// Line is the position of the matching field in the flattened resource.
+	Line int
+	// Path is the path of the matching field, e.g. spec.containers[0].image.
+	Path string
+	// InKey and InValue report whether the pattern matched the key or the
+	// value of the field. Both are set when a match spans the key and the value.
+	InKey   bool
+	InValue bool
+	// Content is the matching field rendered as "key: value".
+	Content string
+	Matches []match.Span
+	// Patterns lists the patterns that matched, which is useful when
+	// searching for several patterns at once.
+	Patterns []string
+	// Before and After hold the context lines surrounding the match.
+	Before []ContextLine
+	After  []ContextLine
+}
+
+// ContextLine is a line surrounding an occurrence.
+type ContextLine struct {
+	Line    int
+	Path    string
+	Content string
 }
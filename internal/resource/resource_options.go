@@ -0,0 +1,54 @@
+package resource
+
+import "github.com/hbelmiro/kgrep/internal/predicate"
+
+// Scope determines what a pattern is evaluated against.
+type Scope string
+
+const (
+	// ScopeLine evaluates patterns against each line of a resource.
+	ScopeLine Scope = "line"
+	// ScopeObject evaluates patterns against a resource as a whole, so the
+	// terms of a query may be satisfied by different lines.
+	ScopeObject Scope = "object"
+)
+
+// Options configures optional Searcher behavior.
+type Options struct {
+	// Scope defaults to ScopeLine.
+	Scope Scope
+	// BeforeContext and AfterContext are the number of lines to include
+	// before and after each matching line.
+	BeforeContext int
+	AfterContext  int
+	// Fields restricts matching to the selected subtrees of each resource,
+	// and ExcludeFields skips the selected subtrees.
+	Fields        []FieldSelector
+	ExcludeFields []FieldSelector
+	// Target defaults to TargetAll.
+	Target Target
+	// Noise configures the parts of resources that are pruned before matching.
+	Noise NoiseFilter
+	// Where only searches the resources satisfying the predicate, which is
+	// evaluated against the whole resource before pruning.
+	Where *predicate.Predicate
+	// LabelSelector and FieldSelector restrict the listed resources. They are
+	// passed to the API server, and field selectors it doesn't support are
+	// evaluated client-side.
+	LabelSelector string
+	FieldSelector string
+	// Annotations only searches resources satisfying all the requirements.
+	Annotations []AnnotationRequirement
+	// Names only searches the resources with the given names. Names may be
+	// glob patterns like web-*.
+	Names []string
+	// Concurrency is the maximum number of namespaces fetched or objects
+	// searched at once. Values below 1 search sequentially.
+	Concurrency int
+	// ChunkSize is the maximum number of resources listed per request. Each
+	// chunk is searched as it arrives. Zero lists all resources at once.
+	ChunkSize int64
+	// OnError is called with the errors that don't stop a search, such as
+	// namespaces that can't be listed. Errors are ignored if it's nil.
+	OnError func(error)
+}
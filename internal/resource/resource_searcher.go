@@ -2,19 +2,26 @@ package resource
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
 
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 
-	"k8s.io/apimachinery/pkg/runtime/serializer/json"
-
-	"github.com/hbelmiro/go-kube-get/pkg/gokubeget"
+	"github.com/hbelmiro/kgrep/internal/kubeconfig"
+	"github.com/hbelmiro/kgrep/internal/match"
+	"github.com/hbelmiro/kgrep/internal/pager"
+	"github.com/hbelmiro/kgrep/internal/worker"
 )
 
 // Searcher is responsible for searching patterns in Kubernetes resources.
@@ -26,16 +33,15 @@ type Searcher struct {
 	clientset     kubernetes.Interface
 	dynamicClient dynamic.Interface
 	config        *rest.Config
-	kubeGet       *gokubeget.KubeGet
+	lister        Lister
+	options       Options
 }
 
 // NewResourceSearcher creates a new ResourceSearcher for the specified resource type.
 func NewResourceSearcher(resourceType string) (*Searcher, error) {
-	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
-	configOverrides := &clientcmd.ConfigOverrides{}
-	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, configOverrides).ClientConfig()
+	config, err := kubeconfig.Load()
 	if err != nil {
-		return nil, fmt.Errorf("error creating Kubernetes config: %v", err)
+		return nil, err
 	}
 
 	clientset, err := kubernetes.NewForConfig(config)
@@ -48,10 +54,10 @@ func NewResourceSearcher(resourceType string) (*Searcher, error) {
 		return nil, fmt.Errorf("error creating dynamic client: %v", err)
 	}
 
-	kubeGet, err := gokubeget.NewKubeGet(config)
+	lister, err := NewDefaultLister(config)
 	if err != nil {
-		// kubeGet is optional for core resources, so we don't return an error here
-		kubeGet = nil
+		// The lister is reported as unavailable when searching, so we don't return an error here
+		lister = nil
 	}
 
 	return &Searcher{
@@ -60,17 +66,15 @@ func NewResourceSearcher(resourceType string) (*Searcher, error) {
 		config:        config,
 		resourceType:  resourceType,
 		kind:          resourceType,
-		kubeGet:       kubeGet,
+		lister:        lister,
 	}, nil
 }
 
 // NewGenericResourceSearcher creates a new ResourceSearcher for generic resources with API version and kind.
 func NewGenericResourceSearcher(apiVersion, kind string) (*Searcher, error) {
-	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
-	configOverrides := &clientcmd.ConfigOverrides{}
-	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, configOverrides).ClientConfig()
+	config, err := kubeconfig.Load()
 	if err != nil {
-		return nil, fmt.Errorf("error creating Kubernetes config: %v", err)
+		return nil, err
 	}
 
 	clientset, err := kubernetes.NewForConfig(config)
@@ -83,10 +87,10 @@ func NewGenericResourceSearcher(apiVersion, kind string) (*Searcher, error) {
 		return nil, fmt.Errorf("error creating dynamic client: %v", err)
 	}
 
-	kubeGet, err := gokubeget.NewKubeGet(config)
+	lister, err := NewDefaultLister(config)
 	if err != nil {
-		// kubeGet is optional for core resources, so we don't return an error here
-		kubeGet = nil
+		// The lister is reported as unavailable when searching, so we don't return an error here
+		lister = nil
 	}
 
 	return &Searcher{
@@ -95,17 +99,15 @@ func NewGenericResourceSearcher(apiVersion, kind string) (*Searcher, error) {
 		config:        config,
 		apiVersion:    apiVersion,
 		kind:          kind,
-		kubeGet:       kubeGet,
+		lister:        lister,
 	}, nil
 }
 
 // NewAutoDiscoveryResourceSearcher creates a new ResourceSearcher that auto-discovers API version and kind.
 func NewAutoDiscoveryResourceSearcher(kind string) (*Searcher, error) {
-	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
-	configOverrides := &clientcmd.ConfigOverrides{}
-	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, configOverrides).ClientConfig()
+	config, err := kubeconfig.Load()
 	if err != nil {
-		return nil, fmt.Errorf("error creating Kubernetes config: %v", err)
+		return nil, err
 	}
 
 	clientset, err := kubernetes.NewForConfig(config)
@@ -118,10 +120,10 @@ func NewAutoDiscoveryResourceSearcher(kind string) (*Searcher, error) {
 		return nil, fmt.Errorf("error creating dynamic client: %v", err)
 	}
 
-	kubeGet, err := gokubeget.NewKubeGet(config)
+	lister, err := NewDefaultLister(config)
 	if err != nil {
-		// kubeGet is optional for core resources, so we don't return an error here
-		kubeGet = nil
+		// The lister is reported as unavailable when searching, so we don't return an error here
+		lister = nil
 	}
 
 	return &Searcher{
@@ -129,86 +131,429 @@ func NewAutoDiscoveryResourceSearcher(kind string) (*Searcher, error) {
 		dynamicClient: dynamicClient,
 		config:        config,
 		kind:          kind,
-		kubeGet:       kubeGet,
+		lister:        lister,
 	}, nil
 }
 
+// SetOptions configures optional search behavior.
+func (s *Searcher) SetOptions(options Options) {
+	s.options = options
+}
+
 // SearchWithoutNamespace searches for a pattern in resources in the default namespace.
-func (s *Searcher) SearchWithoutNamespace(pattern string) ([]Occurrence, error) {
+func (s *Searcher) SearchWithoutNamespace(matcher match.Matcher) ([]Occurrence, error) {
+	return collect(func(emit func(Occurrence)) error {
+		return s.SearchWithoutNamespaceStream(matcher, emit)
+	})
+}
+
+// SearchWithoutNamespaceStream is like SearchWithoutNamespace, but calls emit
+// with each occurrence as it's found.
+func (s *Searcher) SearchWithoutNamespaceStream(matcher match.Matcher, emit func(Occurrence)) error {
 	namespace, err := s.getDefaultNamespace()
 	if err != nil {
-		return nil, fmt.Errorf("error getting default namespace: %v", err)
+		return fmt.Errorf("error getting default namespace: %v", err)
 	}
-	return s.Search(namespace, pattern)
+	return s.SearchStream(namespace, matcher, emit)
 }
 
 // Search searches for a pattern in resources in a specific namespace.
-func (s *Searcher) Search(namespace, pattern string) ([]Occurrence, error) {
+func (s *Searcher) Search(namespace string, matcher match.Matcher) ([]Occurrence, error) {
+	return collect(func(emit func(Occurrence)) error {
+		return s.SearchStream(namespace, matcher, emit)
+	})
+}
+
+// SearchStream is like Search, but calls emit with each occurrence as it's
+// found. Occurrences are emitted in the same order Search returns them, and
+// emit is never called concurrently.
+func (s *Searcher) SearchStream(namespace string, matcher match.Matcher, emit func(Occurrence)) error {
 	if s.clientset == nil {
-		return nil, fmt.Errorf("Kubernetes clientset not available")
+		return fmt.Errorf("Kubernetes clientset not available")
+	}
+
+	return s.searchNamespace(namespace, matcher, emit, nil)
+}
+
+// SearchAllNamespaces searches for a pattern in resources across all namespaces.
+// Namespaces are fetched concurrently and the occurrences are returned in
+// namespace order. Namespaces that fail are reported through Options.OnError
+// and skipped.
+func (s *Searcher) SearchAllNamespaces(matcher match.Matcher) ([]Occurrence, error) {
+	return collect(func(emit func(Occurrence)) error {
+		return s.SearchAllNamespacesStream(matcher, emit)
+	})
+}
+
+// SearchAllNamespacesStream is like SearchAllNamespaces, but calls emit with
+// the occurrences of each namespace once it and the namespaces before it have
+// been searched. Named resources only need to exist in some namespace.
+func (s *Searcher) SearchAllNamespacesStream(matcher match.Matcher, emit func(Occurrence)) error {
+	if s.clientset == nil {
+		return fmt.Errorf("Kubernetes clientset not available")
 	}
 
-	resources, err := s.getGenericResourceNames(namespace)
+	namespaces, err := s.getAllNamespaces()
 	if err != nil {
-		return nil, fmt.Errorf("error getting resources: %v", err)
+		return fmt.Errorf("error getting namespaces: %v", err)
 	}
 
-	var occurrences []Occurrence
-	for _, resource := range resources {
-		resourceOccurrences := s.searchResource(namespace, resource, pattern)
-		occurrences = append(occurrences, resourceOccurrences...)
+	// missing counts the namespaces each exact name wasn't found in.
+	var mu sync.Mutex
+	missing := make(map[string]int)
+	countMissing := func(name string) {
+		mu.Lock()
+		defer mu.Unlock()
+		missing[name]++
+	}
+
+	occurrences := make([][]Occurrence, len(namespaces))
+	tasks := make([]func() error, len(namespaces))
+	for i, namespace := range namespaces {
+		tasks[i] = func() error {
+			return s.searchNamespace(namespace, matcher, func(occurrence Occurrence) {
+				occurrences[i] = append(occurrences[i], occurrence)
+			}, countMissing)
+		}
+	}
+
+	worker.RunOrdered(s.options.Concurrency, tasks, func(i int, err error) {
+		if err != nil {
+			// Continue searching other namespaces even if one fails
+			s.reportError(fmt.Errorf("namespace %s: %v", namespaces[i], err))
+			return
+		}
+		for _, occurrence := range occurrences[i] {
+			emit(occurrence)
+		}
+		occurrences[i] = nil
+	})
+
+	for _, name := range s.options.Names {
+		if len(namespaces) > 0 && missing[name] == len(namespaces) {
+			return &notFoundError{kind: s.kind, name: name}
+		}
 	}
 
+	return nil
+}
+
+// collect gathers the occurrences emitted by a streaming search.
+func collect(search func(emit func(Occurrence)) error) ([]Occurrence, error) {
+	var occurrences []Occurrence
+	if err := search(func(occurrence Occurrence) {
+		occurrences = append(occurrences, occurrence)
+	}); err != nil {
+		return nil, err
+	}
 	return occurrences, nil
 }
 
-// SearchAllNamespaces searches for a pattern in resources across all namespaces.
-func (s *Searcher) SearchAllNamespaces(pattern string) ([]Occurrence, error) {
-	if s.clientset == nil {
-		return nil, fmt.Errorf("Kubernetes clientset not available")
+// searchNamespace searches the resources of a namespace: the resources named
+// in the options, or else all resources of the searcher's kind. Each kind is
+// listed once per namespace, and listed resources are searched chunk by chunk.
+// Missing named resources are handled as in getNamedResources.
+func (s *Searcher) searchNamespace(namespace string, matcher match.Matcher, emit func(Occurrence), missing func(name string)) error {
+	if len(s.options.Names) > 0 {
+		objects, err := s.getNamedResources(namespace, missing)
+		if err != nil {
+			return err
+		}
+		s.searchObjects(namespace, objects, matcher, emit)
+		return nil
 	}
 
-	namespaces, err := s.getAllNamespaces()
+	err := s.listGenericResources(namespace, func(objects []unstructured.Unstructured) error {
+		s.searchObjects(namespace, objects, matcher, emit)
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("error getting namespaces: %v", err)
+		return fmt.Errorf("error getting resources: %v", err)
+	}
+	return nil
+}
+
+// searchObjects searches the objects of a namespace concurrently and emits
+// the occurrences in the order of the objects.
+func (s *Searcher) searchObjects(namespace string, objects []unstructured.Unstructured, matcher match.Matcher, emit func(Occurrence)) {
+	results := make([][]Occurrence, len(objects))
+	tasks := make([]func() error, len(objects))
+	for i := range objects {
+		tasks[i] = func() error {
+			results[i] = s.searchObject(namespace, objects[i].GetName(), objects[i].Object, matcher)
+			return nil
+		}
 	}
 
-	var allOccurrences []Occurrence
-	for _, namespace := range namespaces {
-		occurrences, err := s.Search(namespace, pattern)
+	worker.RunOrdered(s.options.Concurrency, tasks, func(i int, err error) {
 		if err != nil {
-			// Continue searching other namespaces even if one fails
+			s.reportError(fmt.Errorf("%s %s/%s: %v", s.kind, namespace, objects[i].GetName(), err))
+		}
+		for _, occurrence := range results[i] {
+			emit(occurrence)
+		}
+		results[i] = nil
+	})
+}
+
+// reportError reports an error that doesn't stop the search.
+func (s *Searcher) reportError(err error) {
+	if s.options.OnError != nil {
+		s.options.OnError(err)
+	}
+}
+
+// getNamedResources gets the resources named in the options. Exact names are
+// fetched directly, while glob patterns are matched against the names of the
+// listed resources. Exact names that don't exist are passed to missing, or are
+// an error if missing is nil.
+func (s *Searcher) getNamedResources(namespace string, missing func(name string)) ([]unstructured.Unstructured, error) {
+	var objects []unstructured.Unstructured
+	var globs []string
+	for _, name := range s.options.Names {
+		if isGlob(name) {
+			globs = append(globs, name)
+			continue
+		}
+
+		object, err := s.getGenericResource(namespace, name)
+		var notFound *notFoundError
+		if missing != nil && errors.As(err, &notFound) {
+			missing(name)
 			continue
 		}
-		allOccurrences = append(allOccurrences, occurrences...)
+		if err != nil {
+			return nil, err
+		}
+		if object != nil {
+			objects = append(objects, *object)
+		}
+	}
+
+	if len(globs) == 0 {
+		return objects, nil
 	}
 
-	return allOccurrences, nil
+	err := s.listGenericResources(namespace, func(resources []unstructured.Unstructured) error {
+		for _, resource := range resources {
+			if matchesAnyGlob(resource.GetName(), globs) {
+				objects = append(objects, resource)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error getting resources: %v", err)
+	}
+
+	return objects, nil
 }
 
-// searchResource searches for a pattern in a specific resource.
-func (s *Searcher) searchResource(namespace, resource, pattern string) []Occurrence {
-	yaml, err := s.getGenericResourceYAML(namespace, resource)
+// getGenericResource gets a resource by name with a direct GET. Since the API
+// server can't apply selectors to a GET, they are evaluated client-side and
+// nil is returned for resources that don't satisfy them.
+func (s *Searcher) getGenericResource(namespace, name string) (*unstructured.Unstructured, error) {
+	if s.lister == nil {
+		return nil, fmt.Errorf("resource lister not available")
+	}
+
+	object, err := s.lister.Get(context.Background(), s.kind, namespace, name)
+	if apierrors.IsNotFound(err) {
+		return nil, &notFoundError{kind: s.kind, name: name, namespace: namespace}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting %s %q: %v", s.kind, name, err)
+	}
+
+	labelSelector, err := labels.Parse(s.options.LabelSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid label selector: %v", err)
+	}
+	fieldSelector, err := fields.ParseSelector(s.options.FieldSelector)
 	if err != nil {
-		return []Occurrence{}
+		return nil, fmt.Errorf("invalid field selector: %v", err)
+	}
+
+	if !labelSelector.Matches(labels.Set(object.GetLabels())) ||
+		!matchesFieldSelector(object, fieldSelector) ||
+		!matchesAnnotations(object, s.options.Annotations) {
+		return nil, nil
+	}
+
+	return object, nil
+}
+
+// notFoundError reports a named resource that doesn't exist, in a namespace
+// or, without one, anywhere.
+type notFoundError struct {
+	kind, name, namespace string
+}
+
+func (e *notFoundError) Error() string {
+	if e.namespace != "" {
+		return fmt.Sprintf("%s %q not found in namespace %q", e.kind, e.name, e.namespace)
+	}
+	return fmt.Sprintf("%s %q not found", e.kind, e.name)
+}
+
+// searchObject searches for a pattern in an unstructured object.
+// A nil matcher reports every object satisfying the Where predicate as a
+// single occurrence without content.
+func (s *Searcher) searchObject(namespace, resource string, object map[string]interface{}, matcher match.Matcher) []Occurrence {
+	if s.options.Where != nil {
+		if ok, err := s.options.Where.Evaluate(object); !ok || err != nil {
+			return []Occurrence{}
+		}
+	}
+
+	if matcher == nil {
+		return []Occurrence{{Resource: resource, Namespace: namespace}}
+	}
+
+	s.options.Noise.prune(object)
+	fields := flattenObject(object)
+
+	var occurrences []Occurrence
+	if s.options.Scope == ScopeObject {
+		occurrences = s.searchObjectFields(namespace, resource, fields, matcher)
+	} else {
+		occurrences = s.searchFields(namespace, resource, fields, matcher)
+	}
+
+	return s.addContext(occurrences, fields)
+}
+
+// searchFields evaluates the matcher against each selected field of a resource.
+func (s *Searcher) searchFields(namespace, resource string, fields []field, matcher match.Matcher) []Occurrence {
+	var occurrences []Occurrence
+	for i, f := range fields {
+		text, offset, ok := s.matchText(f)
+		if !ok {
+			continue
+		}
+		if spans, ok := matcher.Match(text); ok {
+			occurrences = append(occurrences, newOccurrence(namespace, resource, i, f, shiftSpans(spans, offset)))
+		}
+	}
+
+	return occurrences
+}
+
+// searchObjectFields evaluates the matcher against all selected fields of a resource at once.
+// A matching resource without any matching field, e.g. because the query only
+// excludes terms, is reported as a single occurrence without content.
+func (s *Searcher) searchObjectFields(namespace, resource string, fields []field, matcher match.Matcher) []Occurrence {
+	var texts []string
+	var indexes, offsets []int
+	for i, f := range fields {
+		if text, offset, ok := s.matchText(f); ok {
+			texts = append(texts, text)
+			indexes = append(indexes, i)
+			offsets = append(offsets, offset)
+		}
+	}
+
+	lineMatches, ok := match.MatchDocument(matcher, texts)
+	if !ok {
+		return nil
+	}
+
+	if len(lineMatches) == 0 {
+		return []Occurrence{{Resource: resource, Namespace: namespace}}
 	}
 
 	var occurrences []Occurrence
-	lines := strings.Split(yaml, "\n")
-	for i, line := range lines {
-		if strings.Contains(strings.ToLower(line), strings.ToLower(pattern)) {
-			occurrences = append(occurrences, Occurrence{
-				Resource:  resource,
-				Namespace: namespace,
-				Line:      i + 1,
-				Content:   line,
-			})
+	for _, lineMatch := range lineMatches {
+		index := indexes[lineMatch.Line]
+		spans := shiftSpans(lineMatch.Spans, offsets[lineMatch.Line])
+		occurrences = append(occurrences, newOccurrence(namespace, resource, index, fields[index], spans))
+	}
+
+	return occurrences
+}
+
+// matchText returns the text of a field that patterns are matched against and
+// its offset in the field, or false if the field isn't searched.
+func (s *Searcher) matchText(f field) (string, int, bool) {
+	if !s.options.selected(f) {
+		return "", 0, false
+	}
+	return f.matchText(s.options.Target)
+}
+
+// shiftSpans moves spans found in part of a field's text to their position in the whole text.
+func shiftSpans(spans []match.Span, offset int) []match.Span {
+	if offset == 0 {
+		return spans
+	}
+	for i := range spans {
+		spans[i].Start += offset
+		spans[i].End += offset
+	}
+	return spans
+}
+
+// newOccurrence creates the occurrence of a match in the field at the given index.
+func newOccurrence(namespace, resource string, index int, f field, spans []match.Span) Occurrence {
+	occurrence := Occurrence{
+		Resource:  resource,
+		Namespace: namespace,
+		Line:      index + 1,
+		Path:      f.path,
+		Content:   f.text,
+		Matches:   spans,
+		Patterns:  match.Patterns(spans),
+	}
+
+	for _, span := range spans {
+		occurrence.InKey = occurrence.InKey || f.inKey(span.Start)
+		occurrence.InValue = occurrence.InValue || f.inValue(span.End)
+	}
+
+	return occurrence
+}
+
+// addContext fills the context lines of the occurrences found in a resource.
+// Context stops at neighbouring matches, which are reported on their own, and
+// lines are never reported twice.
+func (s *Searcher) addContext(occurrences []Occurrence, fields []field) []Occurrence {
+	if s.options.BeforeContext <= 0 && s.options.AfterContext <= 0 {
+		return occurrences
+	}
+
+	for i := range occurrences {
+		occurrence := &occurrences[i]
+		if occurrence.Line == 0 {
+			continue
+		}
+
+		// Line numbers are 1-based, so fields[occurrence.Line-1] is the matching field.
+		first := max(occurrence.Line-s.options.BeforeContext, 1)
+		if i > 0 {
+			// Skip the lines already reported with the previous occurrence.
+			previous := occurrences[i-1]
+			first = max(first, previous.Line+len(previous.After)+1)
+		}
+		for line := first; line < occurrence.Line; line++ {
+			occurrence.Before = append(occurrence.Before, newContextLine(line, fields[line-1]))
+		}
+
+		last := min(occurrence.Line+s.options.AfterContext, len(fields))
+		if i+1 < len(occurrences) && occurrences[i+1].Line <= last {
+			last = occurrences[i+1].Line - 1
+		}
+		for line := occurrence.Line + 1; line <= last; line++ {
+			occurrence.After = append(occurrence.After, newContextLine(line, fields[line-1]))
 		}
 	}
 
 	return occurrences
 }
 
+func newContextLine(line int, f field) ContextLine {
+	return ContextLine{Line: line, Path: f.path, Content: f.text}
+}
+
 // getDefaultNamespace gets the default namespace from kubeconfig.
 func (s *Searcher) getDefaultNamespace() (string, error) {
 	if s.config == nil {
@@ -236,143 +581,124 @@ func (s *Searcher) getAllNamespaces() ([]string, error) {
 		return nil, fmt.Errorf("Kubernetes clientset not available")
 	}
 
-	namespaces, err := s.clientset.CoreV1().Namespaces().List(context.Background(), metav1.ListOptions{})
+	var namespaceNames []string
+	err := pager.Pages(context.Background(), metav1.ListOptions{Limit: s.options.ChunkSize},
+		func(ctx context.Context, options metav1.ListOptions) ([]corev1.Namespace, string, error) {
+			namespaces, err := s.clientset.CoreV1().Namespaces().List(ctx, options)
+			if err != nil {
+				return nil, "", err
+			}
+			return namespaces.Items, namespaces.Continue, nil
+		},
+		func(namespace corev1.Namespace) string { return namespace.Name },
+		func(namespaces []corev1.Namespace) error {
+			for _, namespace := range namespaces {
+				namespaceNames = append(namespaceNames, namespace.Name)
+			}
+			return nil
+		})
 	if err != nil {
 		return nil, fmt.Errorf("error listing namespaces: %v", err)
 	}
 
-	var namespaceNames []string
-	for _, namespace := range namespaces.Items {
-		namespaceNames = append(namespaceNames, namespace.Name)
-	}
-
 	return namespaceNames, nil
 }
 
-// getGenericResourceNames gets resource names for generic resources.
-func (s *Searcher) getGenericResourceNames(namespace string) ([]string, error) {
-	if s.kubeGet == nil {
-		return nil, fmt.Errorf("kubeGet client not available")
-	}
-
-	kind := s.kind
-
-	_, resources, err := s.kubeGet.Get(context.Background(), kind, namespace)
-	if err == nil {
-		var names []string
-		for _, resource := range resources.Items {
-			names = append(names, resource.GetName())
-		}
-		return names, nil
-	}
-
-	if namespace != "" {
-		_, resources, err := s.kubeGet.Get(context.Background(), kind, "")
-		if err == nil {
-			var names []string
-			for _, resource := range resources.Items {
-				names = append(names, resource.GetName())
+// list lists the resources of a kind in chunks of Options.ChunkSize, applying
+// the label, field and annotation selectors, and calls visit with each chunk.
+// Field selectors rejected by the API server are evaluated client-side.
+func (s *Searcher) list(kind, namespace string, visit func([]unstructured.Unstructured) error) error {
+	options := metav1.ListOptions{
+		LabelSelector: s.options.LabelSelector,
+		FieldSelector: s.options.FieldSelector,
+		Limit:         s.options.ChunkSize,
+	}
+
+	var fieldSelector fields.Selector
+	err := pager.Pages(context.Background(), options,
+		func(ctx context.Context, options metav1.ListOptions) ([]unstructured.Unstructured, string, error) {
+			if fieldSelector != nil {
+				options.FieldSelector = ""
 			}
-			return names, nil
-		}
-	}
 
-	if strings.Contains(kind, ".") && !strings.Contains(kind, ".v") {
-		parts := strings.Split(kind, ".")
-		if len(parts) >= 2 {
-			resourceName := parts[0]
-			_, resources, err := s.kubeGet.Get(context.Background(), resourceName, namespace)
-			if err == nil {
-				var names []string
-				for _, resource := range resources.Items {
-					names = append(names, resource.GetName())
+			resources, err := s.lister.List(ctx, kind, namespace, options)
+			if err != nil && fieldSelector == nil && options.FieldSelector != "" && apierrors.IsBadRequest(err) {
+				fieldSelector, err = fields.ParseSelector(options.FieldSelector)
+				if err != nil {
+					return nil, "", fmt.Errorf("invalid field selector: %v", err)
 				}
-				return names, nil
+				options.FieldSelector = ""
+				resources, err = s.lister.List(ctx, kind, namespace, options)
+			}
+			if err != nil {
+				return nil, "", err
 			}
-			if namespace != "" {
-				_, resources, err := s.kubeGet.Get(context.Background(), resourceName, "")
-				if err == nil {
-					var names []string
-					for _, resource := range resources.Items {
-						names = append(names, resource.GetName())
-					}
-					return names, nil
+			return resources.Items, resources.GetContinue(), nil
+		},
+		func(resource unstructured.Unstructured) string {
+			return resource.GetNamespace() + "/" + resource.GetName()
+		},
+		func(resources []unstructured.Unstructured) error {
+			if fieldSelector == nil && len(s.options.Annotations) == 0 {
+				return visit(resources)
+			}
+
+			var items []unstructured.Unstructured
+			for _, resource := range resources {
+				if fieldSelector != nil && !matchesFieldSelector(&resource, fieldSelector) {
+					continue
+				}
+				if !matchesAnnotations(&resource, s.options.Annotations) {
+					continue
 				}
+				items = append(items, resource)
 			}
-		}
-	}
+			return visit(items)
+		})
 
-	return nil, fmt.Errorf("error getting %s resources: %v", s.kind, err)
+	return err
 }
 
-// getGenericResourceYAML gets YAML for generic resources.
-func (s *Searcher) getGenericResourceYAML(namespace, name string) (string, error) {
-	if s.kubeGet == nil {
-		return "", fmt.Errorf("kubeGet client not available")
+// listGenericResources lists the resources of the searcher's kind and calls
+// visit with each chunk. Cluster-scoped resources are listed without a
+// namespace and, for kind names with a group suffix like "kind.group", the plain
+// kind is tried as well. Fallbacks are only tried until a chunk is listed.
+func (s *Searcher) listGenericResources(namespace string, visit func([]unstructured.Unstructured) error) error {
+	if s.lister == nil {
+		return fmt.Errorf("resource lister not available")
 	}
 
-	kind := s.kind
-
-	_, resources, err := s.kubeGet.Get(context.Background(), kind, namespace)
-	if err == nil {
-		for _, resource := range resources.Items {
-			if resource.GetName() == name {
-				return s.objectToYAML(&resource)
-			}
-		}
-		return "", fmt.Errorf("%s %s not found", s.kind, name)
+	listed := false
+	tracked := func(resources []unstructured.Unstructured) error {
+		listed = true
+		return visit(resources)
 	}
 
+	kind := s.kind
+	candidates := [][2]string{{kind, namespace}}
 	if namespace != "" {
-		_, resources, err := s.kubeGet.Get(context.Background(), kind, "")
-		if err == nil {
-			for _, resource := range resources.Items {
-				if resource.GetName() == name {
-					return s.objectToYAML(&resource)
-				}
-			}
-			return "", fmt.Errorf("%s %s not found", s.kind, name)
-		}
+		candidates = append(candidates, [2]string{kind, ""})
 	}
-
 	if strings.Contains(kind, ".") && !strings.Contains(kind, ".v") {
-		parts := strings.Split(kind, ".")
-		if len(parts) >= 2 {
-			resourceName := parts[0]
-			_, resources, err := s.kubeGet.Get(context.Background(), resourceName, namespace)
-			if err == nil {
-				for _, resource := range resources.Items {
-					if resource.GetName() == name {
-						return s.objectToYAML(&resource)
-					}
-				}
-				return "", fmt.Errorf("%s %s not found", s.kind, name)
-			}
-			if namespace != "" {
-				_, resources, err := s.kubeGet.Get(context.Background(), resourceName, "")
-				if err == nil {
-					for _, resource := range resources.Items {
-						if resource.GetName() == name {
-							return s.objectToYAML(&resource)
-						}
-					}
-					return "", fmt.Errorf("%s %s not found", s.kind, name)
-				}
-			}
+		resourceName := strings.Split(kind, ".")[0]
+		candidates = append(candidates, [2]string{resourceName, namespace})
+		if namespace != "" {
+			candidates = append(candidates, [2]string{resourceName, ""})
 		}
 	}
 
-	return "", fmt.Errorf("error getting %s resources: %v", s.kind, err)
-}
-
-// objectToYAML converts a runtime.Object to YAML string.
-func (s *Searcher) objectToYAML(obj runtime.Object) (string, error) {
-	serializer := json.NewSerializerWithOptions(json.DefaultMetaFactory, nil, nil, json.SerializerOptions{Yaml: true})
-	data, err := runtime.Encode(serializer, obj)
+	var err error
+	for _, candidate := range candidates {
+		err = s.list(candidate[0], candidate[1], tracked)
+		if err == nil || listed {
+			break
+		}
+	}
 	if err != nil {
-		return "", err
+		return fmt.Errorf("error getting %s resources: %v", s.kind, err)
 	}
-	return string(data), nil
+
+	return nil
 }
 
 // discoverAPIVersionAndKind discovers the API version, correct kind, and resource name for a given kind name.
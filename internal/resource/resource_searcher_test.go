@@ -4,10 +4,20 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/hbelmiro/kgrep/internal/match"
+	"github.com/hbelmiro/kgrep/internal/predicate"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"k8s.io/client-go/kubernetes/fake"
 )
 
+func newMatcher(t *testing.T, pattern string) match.Matcher {
+	t.Helper()
+	matcher, err := match.New(pattern, match.Options{})
+	require.NoError(t, err)
+	return matcher
+}
+
 func TestResourceSearcher_SearchWithoutNamespace(t *testing.T) {
 	clientset := fake.NewClientset()
 	searcher := &Searcher{
@@ -15,9 +25,9 @@ func TestResourceSearcher_SearchWithoutNamespace(t *testing.T) {
 		resourceType: "pods",
 	}
 
-	_, err := searcher.SearchWithoutNamespace("test")
+	_, err := searcher.SearchWithoutNamespace(newMatcher(t, "test"))
 	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "kubeGet client not available")
+	assert.Contains(t, err.Error(), "resource lister not available")
 }
 
 func TestResourceSearcher_SearchWithNamespace(t *testing.T) {
@@ -27,9 +37,9 @@ func TestResourceSearcher_SearchWithNamespace(t *testing.T) {
 		resourceType: "pods",
 	}
 
-	_, err := searcher.Search("default", "test")
+	_, err := searcher.Search("default", newMatcher(t, "test"))
 	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "kubeGet client not available")
+	assert.Contains(t, err.Error(), "resource lister not available")
 }
 
 func TestResourceSearcher_SearchAllNamespaces(t *testing.T) {
@@ -39,8 +49,8 @@ func TestResourceSearcher_SearchAllNamespaces(t *testing.T) {
 		resourceType: "pods",
 	}
 
-	_, err := searcher.SearchAllNamespaces("test")
-	// Should succeed in getting namespaces but fail on kubeGet
+	_, err := searcher.SearchAllNamespaces(newMatcher(t, "test"))
+	// Should succeed in getting namespaces but fail on the lister
 	assert.NoError(t, err)
 }
 
@@ -49,7 +59,7 @@ func TestResourceSearcher_SearchAllNamespaces_NoClientset(t *testing.T) {
 		resourceType: "pods",
 	}
 
-	_, err := searcher.SearchAllNamespaces("test")
+	_, err := searcher.SearchAllNamespaces(newMatcher(t, "test"))
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "Kubernetes clientset not available")
 }
@@ -76,12 +86,12 @@ func TestResourceOccurrence(t *testing.T) {
 	assert.Equal(t, "test content", occurrence.Content)
 }
 
-func TestResourceSearcher_GetGenericResourceNames_Error(t *testing.T) {
+func TestResourceSearcher_ListGenericResources_Error(t *testing.T) {
 	searcher := &Searcher{resourceType: "unknown"}
 
-	_, err := searcher.getGenericResourceNames("default")
+	err := searcher.listGenericResources("default", nil)
 	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "kubeGet client not available")
+	assert.Contains(t, err.Error(), "resource lister not available")
 }
 
 func TestResourceSearcher_GetDefaultNamespace_NoConfig(t *testing.T) {
@@ -111,7 +121,7 @@ func TestDiscoverAPIVersionAndKind_WithFakeClientset(t *testing.T) {
 		strings.Contains(err.Error(), "error getting API groups"))
 }
 
-func TestGetGenericResourceNames_HelpfulErrorMessage(t *testing.T) {
+func TestGetGenericResources_HelpfulErrorMessage(t *testing.T) {
 	// Test that the constructor correctly handles different resource name formats
 	testCases := []struct {
 		name          string
@@ -136,9 +146,9 @@ func TestGetGenericResourceNames_HelpfulErrorMessage(t *testing.T) {
 				kind: tc.malformedKind,
 			}
 
-			_, err := searcher.getGenericResourceNames("default")
+			err := searcher.listGenericResources("default", nil)
 			assert.Error(t, err)
-			assert.Contains(t, err.Error(), "kubeGet client not available")
+			assert.Contains(t, err.Error(), "resource lister not available")
 
 			// Verify that the searcher has the expected kind stored
 			assert.Equal(t, tc.malformedKind, searcher.kind)
@@ -220,46 +230,46 @@ func TestResourceSearcher_HandlesCoreResourceTypes(t *testing.T) {
 				kind:      tc.kind,
 			}
 
-			_, err := searcher.getGenericResourceNames("default")
+			err := searcher.listGenericResources("default", nil)
 			assert.Error(t, err)
-			assert.Contains(t, err.Error(), "kubeGet client not available")
+			assert.Contains(t, err.Error(), "resource lister not available")
 		})
 	}
 }
 
-func TestGetGenericResourceYAML_ErrorHandling(t *testing.T) {
+func TestGetGenericResource_ErrorHandling(t *testing.T) {
 	searcher := &Searcher{
 		kind: "customresource",
 	}
 
-	_, err := searcher.getGenericResourceYAML("default", "test-resource")
+	_, err := searcher.getGenericResource("default", "test-resource")
 	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "kubeGet client not available")
+	assert.Contains(t, err.Error(), "resource lister not available")
 }
 
-func TestGetGenericResourceNames_ClusterScopedFallback(t *testing.T) {
+func TestListGenericResources_ClusterScopedFallback(t *testing.T) {
 	searcher := &Searcher{
-		kind:    "namespace",
-		kubeGet: nil,
+		kind:   "namespace",
+		lister: nil,
 	}
 
-	_, err := searcher.getGenericResourceNames("some-namespace")
+	err := searcher.listGenericResources("some-namespace", nil)
 	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "kubeGet client not available")
+	assert.Contains(t, err.Error(), "resource lister not available")
 }
 
-func TestGetGenericResourceYAML_ClusterScopedFallback(t *testing.T) {
+func TestGetGenericResource_ClusterScopedFallback(t *testing.T) {
 	searcher := &Searcher{
-		kind:    "namespace",
-		kubeGet: nil,
+		kind:   "namespace",
+		lister: nil,
 	}
 
-	_, err := searcher.getGenericResourceYAML("some-namespace", "some-name")
+	_, err := searcher.getGenericResource("some-namespace", "some-name")
 	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "kubeGet client not available")
+	assert.Contains(t, err.Error(), "resource lister not available")
 }
 
-func TestGetGenericResourceNames_KindBasedRouting(t *testing.T) {
+func TestGetGenericResources_KindBasedRouting(t *testing.T) {
 	clientset := fake.NewClientset()
 
 	testCases := []struct {
@@ -296,14 +306,14 @@ func TestGetGenericResourceNames_KindBasedRouting(t *testing.T) {
 				apiVersion: "",
 			}
 
-			_, err := searcher.getGenericResourceNames("default")
+			err := searcher.listGenericResources("default", nil)
 			assert.Error(t, err)
-			assert.Contains(t, err.Error(), "kubeGet client not available")
+			assert.Contains(t, err.Error(), "resource lister not available")
 		})
 	}
 }
 
-func TestGetGenericResourceYAML_KindBasedRouting(t *testing.T) {
+func TestGetGenericResource_KindBasedRouting(t *testing.T) {
 	clientset := fake.NewClientset()
 
 	testCases := []struct {
@@ -340,9 +350,9 @@ func TestGetGenericResourceYAML_KindBasedRouting(t *testing.T) {
 				apiVersion: "",
 			}
 
-			_, err := searcher.getGenericResourceYAML("default", "test-resource")
+			_, err := searcher.getGenericResource("default", "test-resource")
 			assert.Error(t, err)
-			assert.Contains(t, err.Error(), "kubeGet client not available")
+			assert.Contains(t, err.Error(), "resource lister not available")
 		})
 	}
 }
@@ -354,14 +364,121 @@ func TestSearcher_APIVersionAndKindPrecedence(t *testing.T) {
 		clientset:  clientset,
 		apiVersion: "v1",
 		kind:       "Pod",
-		kubeGet:    nil,
+		lister:     nil,
 	}
 
-	_, err := searcher.getGenericResourceNames("default")
+	err := searcher.listGenericResources("default", nil)
 	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "kubeGet client not available")
+	assert.Contains(t, err.Error(), "resource lister not available")
 
-	_, err = searcher.getGenericResourceYAML("default", "test-resource")
+	_, err = searcher.getGenericResource("default", "test-resource")
 	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "kubeGet client not available")
+	assert.Contains(t, err.Error(), "resource lister not available")
+}
+
+func TestSearchObjectFields(t *testing.T) {
+	searcher := &Searcher{}
+	fields := flattenObject(map[string]interface{}{
+		"kind": "Secret",
+		"stringData": map[string]interface{}{
+			"host":     "postgres.local",
+			"password": "hunter2",
+		},
+	})
+
+	query, err := match.ParseQuery("postgres AND password", match.Options{})
+	require.NoError(t, err)
+
+	occurrences := searcher.searchObjectFields("default", "db", fields, query)
+	assert.Equal(t, []Occurrence{
+		{Resource: "db", Namespace: "default", Line: 3, Path: "stringData.host", InValue: true, Content: "host: postgres.local", Matches: []match.Span{{Start: 6, End: 14, Pattern: "postgres"}}, Patterns: []string{"postgres"}},
+		{Resource: "db", Namespace: "default", Line: 4, Path: "stringData.password", InKey: true, Content: "password: hunter2", Matches: []match.Span{{Start: 0, End: 8, Pattern: "password"}}, Patterns: []string{"password"}},
+	}, occurrences)
+
+	occurrences = searcher.searchObjectFields("default", "db", fields, match.Not(newMatcher(t, "mysql")))
+	assert.Equal(t, []Occurrence{{Resource: "db", Namespace: "default"}}, occurrences)
+
+	occurrences = searcher.searchObjectFields("default", "db", fields, newMatcher(t, "mysql"))
+	assert.Empty(t, occurrences)
+}
+
+func TestSearchFields_KeysAndValues(t *testing.T) {
+	searcher := &Searcher{}
+	fields := flattenObject(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels": map[string]interface{}{"app": "web"},
+		},
+		"spec": map[string]interface{}{
+			"app": "application",
+		},
+	})
+
+	occurrences := searcher.searchFields("default", "web", fields, newMatcher(t, "app"))
+	require.Len(t, occurrences, 2)
+
+	assert.Equal(t, "metadata.labels.app", occurrences[0].Path)
+	assert.True(t, occurrences[0].InKey)
+	assert.False(t, occurrences[0].InValue)
+
+	assert.Equal(t, "spec.app", occurrences[1].Path)
+	assert.True(t, occurrences[1].InKey)
+	assert.True(t, occurrences[1].InValue)
+}
+
+func TestAddContext(t *testing.T) {
+	searcher := &Searcher{options: Options{BeforeContext: 2, AfterContext: 1}}
+	fields := flattenObject(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"containers": []interface{}{
+				map[string]interface{}{
+					"name":  "app",
+					"image": "nginx:latest",
+					"env": []interface{}{
+						map[string]interface{}{"name": "IMAGE", "value": "nginx:latest"},
+					},
+				},
+			},
+		},
+		"status": map[string]interface{}{},
+	})
+
+	occurrences := searcher.searchFields("default", "web", fields, newMatcher(t, "nginx"))
+	occurrences = searcher.addContext(occurrences, fields)
+	require.Len(t, occurrences, 2)
+
+	assert.Equal(t, "spec.containers[0].env[0].value", occurrences[0].Path)
+	assert.Equal(t, []ContextLine{
+		{Line: 3, Path: "spec.containers[0].env", Content: "env:"},
+		{Line: 4, Path: "spec.containers[0].env[0].name", Content: "name: IMAGE"},
+	}, occurrences[0].Before)
+	assert.Empty(t, occurrences[0].After, "the next field is a match and is reported on its own")
+
+	assert.Empty(t, occurrences[1].Before)
+	assert.Equal(t, []ContextLine{{Line: 7, Path: "spec.containers[0].name", Content: "name: app"}}, occurrences[1].After)
+}
+
+func TestSearchObject_Where(t *testing.T) {
+	where, err := predicate.New("object.spec.replicas > 3")
+	require.NoError(t, err)
+	searcher := &Searcher{options: Options{Where: where}}
+
+	newObject := func(replicas int64) map[string]interface{} {
+		return map[string]interface{}{
+			"spec":   map[string]interface{}{"replicas": replicas, "image": "nginx"},
+			"status": map[string]interface{}{"replicas": replicas},
+		}
+	}
+
+	assert.Empty(t, searcher.searchObject("default", "small", newObject(2), newMatcher(t, "nginx")))
+
+	occurrences := searcher.searchObject("default", "large", newObject(5), newMatcher(t, "nginx"))
+	require.Len(t, occurrences, 1)
+	assert.Equal(t, "spec.image", occurrences[0].Path)
+
+	// Without a pattern, every object satisfying the predicate is reported.
+	occurrences = searcher.searchObject("default", "large", newObject(5), nil)
+	assert.Equal(t, []Occurrence{{Resource: "large", Namespace: "default"}}, occurrences)
+
+	// Evaluation errors exclude the object.
+	assert.Empty(t, searcher.searchObject("default", "broken", map[string]interface{}{}, nil))
 }
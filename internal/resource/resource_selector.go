@@ -0,0 +1,167 @@
+package resource
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Target determines which part of a field patterns are matched against.
+type Target string
+
+const (
+	// TargetAll matches patterns against keys and values.
+	TargetAll Target = ""
+	// TargetKeys matches patterns against keys only.
+	TargetKeys Target = "keys"
+	// TargetValues matches patterns against values only.
+	TargetValues Target = "values"
+)
+
+// pathSegment is a map key or a list index in the path of a field.
+type pathSegment struct {
+	key string
+	// index is the list index, or -1 for map keys.
+	index int
+}
+
+// FieldSelector selects a subtree of a resource using a JSONPath-like
+// expression such as spec.template.spec.containers[*].image or
+// metadata.annotations["example.com/owner"].
+type FieldSelector struct {
+	expression string
+	segments   []selectorSegment
+}
+
+type selectorSegment struct {
+	key      string
+	index    int
+	wildcard bool
+}
+
+// ParseFieldSelector parses a field selector. Keys are separated by dots, list
+// items are selected with [N], any key or item with * or [*], and keys
+// containing dots can be quoted in brackets or escaped with a backslash.
+// A leading $ or dot and enclosing braces, as in kubectl's JSONPath, are accepted.
+func ParseFieldSelector(expression string) (FieldSelector, error) {
+	selector := FieldSelector{expression: expression}
+
+	s := strings.TrimSpace(expression)
+	if strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}") {
+		s = s[1 : len(s)-1]
+	}
+	s = strings.TrimPrefix(s, "$")
+
+	for i := 0; i < len(s); {
+		switch s[i] {
+		case '.':
+			i++
+			if i == len(s) {
+				if len(selector.segments) == 0 {
+					// "." selects the whole object.
+					return selector, nil
+				}
+				return FieldSelector{}, fmt.Errorf("invalid field selector %q: trailing '.'", expression)
+			}
+			if s[i] == '.' || s[i] == '[' {
+				return FieldSelector{}, fmt.Errorf("invalid field selector %q: empty key at position %d", expression, i)
+			}
+		case '[':
+			end := strings.IndexByte(s[i:], ']')
+			if end < 0 {
+				return FieldSelector{}, fmt.Errorf("invalid field selector %q: missing ']'", expression)
+			}
+			segment, err := parseBracket(s[i+1 : i+end])
+			if err != nil {
+				return FieldSelector{}, fmt.Errorf("invalid field selector %q: %v", expression, err)
+			}
+			selector.segments = append(selector.segments, segment)
+			i += end + 1
+		default:
+			var key strings.Builder
+			for ; i < len(s) && s[i] != '.' && s[i] != '['; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				key.WriteByte(s[i])
+			}
+			if key.String() == "*" {
+				selector.segments = append(selector.segments, selectorSegment{wildcard: true})
+			} else {
+				selector.segments = append(selector.segments, selectorSegment{key: key.String(), index: -1})
+			}
+		}
+	}
+
+	if len(selector.segments) == 0 {
+		return FieldSelector{}, fmt.Errorf("invalid field selector %q: empty selector", expression)
+	}
+
+	return selector, nil
+}
+
+// parseBracket parses the content of a bracketed segment: an index, a wildcard or a quoted key.
+func parseBracket(content string) (selectorSegment, error) {
+	content = strings.TrimSpace(content)
+	switch {
+	case content == "*":
+		return selectorSegment{wildcard: true}, nil
+	case len(content) >= 2 && (content[0] == '"' || content[0] == '\'') && content[len(content)-1] == content[0]:
+		return selectorSegment{key: content[1 : len(content)-1], index: -1}, nil
+	default:
+		index, err := strconv.Atoi(content)
+		if err != nil || index < 0 {
+			return selectorSegment{}, fmt.Errorf("invalid index %q", content)
+		}
+		return selectorSegment{index: index}, nil
+	}
+}
+
+// String returns the expression the selector was parsed from.
+func (s FieldSelector) String() string {
+	return s.expression
+}
+
+// contains reports whether a field lies within the subtree selected by s.
+func (s FieldSelector) contains(f field) bool {
+	if len(f.segments) < len(s.segments) {
+		return false
+	}
+
+	for i, segment := range s.segments {
+		if !segment.matches(f.segments[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s selectorSegment) matches(segment pathSegment) bool {
+	if s.wildcard {
+		return true
+	}
+	if s.index >= 0 {
+		return segment.index == s.index
+	}
+	return segment.index < 0 && segment.key == s.key
+}
+
+// selected reports whether a field is within the fields selected by the options.
+// Without selectors every field is selected.
+func (o Options) selected(f field) bool {
+	for _, selector := range o.ExcludeFields {
+		if selector.contains(f) {
+			return false
+		}
+	}
+
+	if len(o.Fields) == 0 {
+		return true
+	}
+	for _, selector := range o.Fields {
+		if selector.contains(f) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,132 @@
+package resource
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFieldSelector(t *testing.T) {
+	fields := flattenObject(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{
+				"example.com/owner": "team-a",
+			},
+			"name": "web",
+		},
+		"spec": map[string]interface{}{
+			"containers": []interface{}{
+				map[string]interface{}{"name": "app", "image": "app:1.0"},
+				map[string]interface{}{"name": "proxy", "image": "envoy:1.30"},
+			},
+		},
+	})
+
+	testCases := []struct {
+		selector string
+		paths    []string
+	}{
+		{
+			selector: "spec.containers[*].image",
+			paths:    []string{"spec.containers[0].image", "spec.containers[1].image"},
+		},
+		{
+			selector: "{.spec.containers[1].name}",
+			paths:    []string{"spec.containers[1].name"},
+		},
+		{
+			selector: "metadata.annotations",
+			paths:    []string{"metadata.annotations", `metadata.annotations["example.com/owner"]`},
+		},
+		{
+			selector: `metadata.annotations["example.com/owner"]`,
+			paths:    []string{`metadata.annotations["example.com/owner"]`},
+		},
+		{
+			selector: `$.metadata.annotations.example\.com/owner`,
+			paths:    []string{`metadata.annotations["example.com/owner"]`},
+		},
+		{
+			selector: "*.name",
+			paths:    []string{"metadata.name"},
+		},
+		{
+			selector: "spec.containers.image",
+			paths:    nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.selector, func(t *testing.T) {
+			selector, err := ParseFieldSelector(tc.selector)
+			require.NoError(t, err)
+
+			var paths []string
+			for _, f := range fields {
+				if selector.contains(f) {
+					paths = append(paths, f.path)
+				}
+			}
+			assert.Equal(t, tc.paths, paths)
+		})
+	}
+}
+
+func TestParseFieldSelector_Errors(t *testing.T) {
+	for _, selector := range []string{"", "spec.", "spec..name", "spec.containers[", "spec.containers[x]", "spec.containers[-1]"} {
+		t.Run(selector, func(t *testing.T) {
+			_, err := ParseFieldSelector(selector)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestSearchFields_SelectorsAndTargets(t *testing.T) {
+	fields := flattenObject(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels": map[string]interface{}{"image": "nginx"},
+		},
+		"spec": map[string]interface{}{
+			"containers": []interface{}{
+				map[string]interface{}{"name": "nginx", "image": "nginx:latest"},
+			},
+		},
+	})
+
+	images, err := ParseFieldSelector("spec.containers[*].image")
+	require.NoError(t, err)
+	labels, err := ParseFieldSelector("metadata.labels")
+	require.NoError(t, err)
+
+	paths := func(occurrences []Occurrence) []string {
+		var paths []string
+		for _, occurrence := range occurrences {
+			paths = append(paths, occurrence.Path)
+		}
+		return paths
+	}
+
+	searcher := &Searcher{options: Options{Fields: []FieldSelector{images}}}
+	assert.Equal(t, []string{"spec.containers[0].image"}, paths(searcher.searchFields("default", "web", fields, newMatcher(t, "nginx"))))
+
+	searcher = &Searcher{options: Options{ExcludeFields: []FieldSelector{labels}}}
+	assert.Equal(t, []string{"spec.containers[0].image", "spec.containers[0].name"}, paths(searcher.searchFields("default", "web", fields, newMatcher(t, "nginx"))))
+
+	searcher = &Searcher{options: Options{Target: TargetKeys}}
+	assert.Equal(t, []string{"metadata.labels.image", "spec.containers[0].image"}, paths(searcher.searchFields("default", "web", fields, newMatcher(t, "image"))))
+
+	searcher = &Searcher{options: Options{Target: TargetValues}}
+	occurrences := searcher.searchFields("default", "web", fields, newMatcher(t, "nginx"))
+	assert.Equal(t, []string{"metadata.labels.image", "spec.containers[0].image", "spec.containers[0].name"}, paths(occurrences))
+	// Spans are relative to the whole field so highlighting lines up with the content.
+	assert.Equal(t, "image: nginx", occurrences[0].Content)
+	assert.Equal(t, 7, occurrences[0].Matches[0].Start)
+
+	assert.Empty(t, searcher.searchFields("default", "web", fields, newMatcher(t, "image")))
+
+	searcher = &Searcher{options: Options{Scope: ScopeObject, Target: TargetValues}}
+	occurrences = searcher.searchObjectFields("default", "web", fields, newMatcher(t, "latest"))
+	require.Len(t, occurrences, 1)
+	assert.Equal(t, 13, occurrences[0].Matches[0].Start)
+}
@@ -0,0 +1,67 @@
+// Package worker runs independent tasks on a bounded pool of goroutines.
+package worker
+
+import "fmt"
+
+// Run runs the tasks with at most concurrency of them at a time and waits for
+// all of them to finish. The returned errors are in the same order as the
+// tasks, with nil for tasks that succeeded, so results stored by index are
+// deterministic regardless of scheduling. A panicking task is reported as an
+// error instead of crashing the program. A concurrency below 1 runs the tasks
+// sequentially.
+func Run(concurrency int, tasks []func() error) []error {
+	errs := make([]error, len(tasks))
+	RunOrdered(concurrency, tasks, func(i int, err error) {
+		errs[i] = err
+	})
+	return errs
+}
+
+// RunOrdered runs the tasks like Run, and calls done with the index and error
+// of each task in task order, as soon as the task and all the tasks before it
+// have finished. This allows for consuming the results of the first tasks
+// while later ones are still running. done is called from the calling
+// goroutine, and RunOrdered returns once done was called for every task.
+func RunOrdered(concurrency int, tasks []func() error, done func(i int, err error)) {
+	if len(tasks) == 0 {
+		return
+	}
+
+	errs := make([]error, len(tasks))
+	indexes := make(chan int)
+	finished := make(chan int)
+	for range min(max(concurrency, 1), len(tasks)) {
+		go func() {
+			for i := range indexes {
+				errs[i] = run(tasks[i])
+				finished <- i
+			}
+		}()
+	}
+
+	go func() {
+		for i := range tasks {
+			indexes <- i
+		}
+		close(indexes)
+	}()
+
+	completed := make([]bool, len(tasks))
+	next := 0
+	for range tasks {
+		completed[<-finished] = true
+		for next < len(tasks) && completed[next] {
+			done(next, errs[next])
+			next++
+		}
+	}
+}
+
+func run(task func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("task panicked: %v", r)
+		}
+	}()
+	return task()
+}
@@ -0,0 +1,104 @@
+package worker
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun_ResultsAndErrorsKeepTaskOrder(t *testing.T) {
+	results := make([]int, 20)
+	var tasks []func() error
+	for i := range results {
+		tasks = append(tasks, func() error {
+			// Later tasks finish first.
+			time.Sleep(time.Duration(len(results)-i) * time.Millisecond)
+			results[i] = i * i
+			if i%5 == 0 {
+				return fmt.Errorf("task %d failed", i)
+			}
+			return nil
+		})
+	}
+
+	errs := Run(4, tasks)
+
+	require.Len(t, errs, len(tasks))
+	for i := range results {
+		assert.Equal(t, i*i, results[i])
+		if i%5 == 0 {
+			assert.EqualError(t, errs[i], fmt.Sprintf("task %d failed", i))
+		} else {
+			assert.NoError(t, errs[i])
+		}
+	}
+}
+
+func TestRun_BoundsConcurrency(t *testing.T) {
+	var running, peak atomic.Int32
+	var tasks []func() error
+	for range 30 {
+		tasks = append(tasks, func() error {
+			current := running.Add(1)
+			for {
+				previous := peak.Load()
+				if current <= previous || peak.CompareAndSwap(previous, current) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			running.Add(-1)
+			return nil
+		})
+	}
+
+	Run(3, tasks)
+	assert.LessOrEqual(t, peak.Load(), int32(3))
+
+	peak.Store(0)
+	Run(0, tasks)
+	assert.Equal(t, int32(1), peak.Load(), "a concurrency below 1 runs tasks sequentially")
+}
+
+func TestRun_CapturesPanics(t *testing.T) {
+	errs := Run(2, []func() error{
+		func() error { return nil },
+		func() error { panic("boom") },
+	})
+
+	assert.NoError(t, errs[0])
+	assert.EqualError(t, errs[1], "task panicked: boom")
+}
+
+func TestRun_NoTasks(t *testing.T) {
+	assert.Empty(t, Run(4, nil))
+}
+
+func TestRunOrdered_CallsDoneInTaskOrderAsSoonAsPossible(t *testing.T) {
+	release := make(chan struct{})
+	tasks := []func() error{
+		func() error { return nil },
+		func() error { <-release; return fmt.Errorf("task 1 failed") },
+		func() error { return nil },
+	}
+
+	var order []int
+	var errs []error
+	RunOrdered(3, tasks, func(i int, err error) {
+		if i == 0 {
+			// The first task is done while the second one is still running.
+			close(release)
+		}
+		order = append(order, i)
+		errs = append(errs, err)
+	})
+
+	assert.Equal(t, []int{0, 1, 2}, order)
+	assert.NoError(t, errs[0])
+	assert.EqualError(t, errs[1], "task 1 failed")
+	assert.NoError(t, errs[2])
+}
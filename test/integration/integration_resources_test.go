@@ -205,6 +205,7 @@ func TestIntegration_CustomResourceSearchContent(t *testing.T) {
 		name     string
 		pattern  string
 		expected string
+		args     []string
 	}{
 		{
 			name:     "search in labels",
@@ -225,12 +226,14 @@ func TestIntegration_CustomResourceSearchContent(t *testing.T) {
 			name:     "search in status",
 			pattern:  "ApplicationReady",
 			expected: "test-app",
+			args:     []string{"--include-status"},
 		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			output, err := runKgrepCommand(t, "resources", "--kind", "TestApplication", "--pattern", tc.pattern, "--namespace", testNamespace)
+			args := append([]string{"resources", "--kind", "TestApplication", "--pattern", tc.pattern, "--namespace", testNamespace}, tc.args...)
+			output, err := runKgrepCommand(t, args...)
 			require.NoError(t, err, "kgrep command failed for %s: %s", tc.name, output)
 
 			assert.Contains(t, output, tc.expected)